@@ -0,0 +1,467 @@
+package rss
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AggregatorConfig holds the settings for an
+// Aggregator.
+type AggregatorConfig struct {
+	// Workers is the most feeds that are updated
+	// at once. If zero, it defaults to 4.
+	Workers int
+
+	// HostLimit is the most feeds from the same
+	// host that are updated at once. If zero, it
+	// defaults to 1.
+	HostLimit int
+
+	// OnItems, if set, is called with the new items
+	// found in each update. Otherwise, they are sent
+	// on the channel returned by Aggregator.Items.
+	// OnItems may be called from several goroutines
+	// at once.
+	OnItems func(f *Feed, items []*Item)
+
+	// OnError, if set, is called when a feed fails
+	// to update. It may be called from several
+	// goroutines at once.
+	OnError func(f *Feed, err error)
+
+	// Store, if set, is where subscribed feeds are
+	// saved, unless they have a Store of their own.
+	// Feeds are removed from it when they are
+	// unsubscribed. See Aggregator.Load.
+	Store Store
+}
+
+// NewItems holds the new items found when
+// updating a feed.
+type NewItems struct {
+	Feed    *Feed
+	Items   []*Item
+	Changes *Changes // All of the update's changes, including Items.
+}
+
+// An Aggregator keeps a set of feeds up to date.
+// Each feed is updated when its Refresh time is
+// reached, using its own FetchFunc or RequestFunc
+// as Feed.Update does, and any new items are
+// delivered.
+//
+// If a feed moves permanently, its subscription
+// follows it, and if it is gone (see ErrGone), it
+// is unsubscribed once OnError has been told.
+//
+// Subscribed feeds should only be used through
+// their methods, such as MarkRead, which are safe
+// to call while they are being updated.
+type Aggregator struct {
+	config AggregatorConfig
+	items  chan NewItems
+
+	ctx    context.Context // Cancelled to abandon updates in progress.
+	cancel context.CancelFunc
+	wake   chan struct{}
+	done   chan struct{} // Closed once run returns.
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	stopped  bool
+	subs     map[string]*subscription // By URL.
+	queue    subscriptionQueue
+	inFlight int
+	hosts    map[string]int // Updates in progress, by host.
+}
+
+type subscription struct {
+	feed  *Feed
+	url   string
+	host  string
+	next  time.Time
+	index int // Index in the queue, or -1 while being updated.
+}
+
+// NewAggregator returns an Aggregator with no
+// feeds, which is ready to use.
+func NewAggregator(config AggregatorConfig) *Aggregator {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.HostLimit <= 0 {
+		config.HostLimit = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &Aggregator{
+		config: config,
+		items:  make(chan NewItems, config.Workers),
+		ctx:    ctx,
+		cancel: cancel,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		subs:   make(map[string]*subscription),
+		hosts:  make(map[string]int),
+	}
+
+	go a.run()
+
+	return a
+}
+
+// Add subscribes to f. It is first updated at
+// f.Refresh, which is immediately if it is zero.
+// If AggregatorConfig.Store is set, f is saved
+// to it first.
+func (a *Aggregator) Add(f *Feed) error {
+	return a.add(f, true)
+}
+
+// Load subscribes to every feed in the Store
+// given in the AggregatorConfig, which would
+// usually have been added in an earlier run.
+func (a *Aggregator) Load() error {
+	if a.config.Store == nil {
+		return ErrNoStore
+	}
+
+	feeds, err := LoadFeeds(a.config.Store)
+	if err != nil {
+		return err
+	}
+	for _, f := range feeds {
+		if err := a.add(f, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Aggregator) add(f *Feed, save bool) error {
+	f.mu.RLock()
+	url, refresh := f.UpdateURL, f.Refresh
+	f.mu.RUnlock()
+
+	if url == "" {
+		return ErrNoURL
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stopped {
+		return ErrStopped
+	}
+	if _, ok := a.subs[url]; ok {
+		return errors.New("already subscribed to " + url)
+	}
+
+	if a.config.Store != nil {
+		f.mu.Lock()
+		if f.Store == nil {
+			f.Store = a.config.Store
+		}
+		var err error
+		if save {
+			err = f.save()
+		}
+		f.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	sub := &subscription{
+		feed: f,
+		url:  url,
+		host: hostOf(url),
+		next: refresh,
+	}
+	a.subs[sub.url] = sub
+	heap.Push(&a.queue, sub)
+	a.signal()
+
+	return nil
+}
+
+// Remove unsubscribes from the feed with the given
+// URL, reporting whether there was one. If the feed
+// is being updated, the update is allowed to finish
+// but no items are delivered.
+func (a *Aggregator) Remove(url string) bool {
+	a.mu.Lock()
+	sub, ok := a.subs[url]
+	if ok {
+		delete(a.subs, url)
+		if sub.index >= 0 {
+			heap.Remove(&a.queue, sub.index)
+		}
+	}
+	a.mu.Unlock()
+
+	if ok {
+		a.signal()
+		a.forget(sub.feed, url)
+	}
+
+	return ok
+}
+
+// forget removes the feed with the given URL
+// from AggregatorConfig.Store, if it is set.
+func (a *Aggregator) forget(f *Feed, url string) {
+	if a.config.Store == nil {
+		return
+	}
+	if err := a.config.Store.DeleteFeed(url); err != nil && a.config.OnError != nil {
+		a.config.OnError(f, err)
+	}
+}
+
+// List returns the subscribed feeds, ordered by URL.
+func (a *Aggregator) List() []*Feed {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	urls := make([]string, 0, len(a.subs))
+	for url := range a.subs {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	out := make([]*Feed, len(urls))
+	for i, url := range urls {
+		out[i] = a.subs[url].feed
+	}
+	return out
+}
+
+// Items returns the channel on which new items are
+// delivered, if AggregatorConfig.OnItems is not set.
+// It is closed once the Aggregator has stopped.
+func (a *Aggregator) Items() <-chan NewItems {
+	return a.items
+}
+
+// Stop stops the Aggregator and waits for any
+// updates in progress to finish. If ctx is done
+// first, those updates are cancelled and Stop
+// returns ctx.Err() once they have returned.
+func (a *Aggregator) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	a.stopped = true
+	a.mu.Unlock()
+	a.signal()
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		a.cancel()
+		<-a.done
+		return ctx.Err()
+	}
+}
+
+// signal wakes the scheduler.
+func (a *Aggregator) signal() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run is the scheduler, which starts updates
+// as feeds become due.
+func (a *Aggregator) run() {
+	defer close(a.done)
+	defer close(a.items)
+	defer a.cancel()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		a.mu.Lock()
+		if a.stopped {
+			a.mu.Unlock()
+			a.wg.Wait()
+			return
+		}
+		wait := a.dispatch(time.Now())
+		a.mu.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-a.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// dispatch starts updating the feeds that are due
+// at now, as far as the limits allow, and returns
+// how long to wait until the next is due. It must
+// be called with a.mu held.
+func (a *Aggregator) dispatch(now time.Time) time.Duration {
+	var blocked []*subscription
+	wait := time.Hour
+	for a.queue.Len() > 0 && a.inFlight < a.config.Workers {
+		sub := a.queue[0]
+		if sub.next.After(now) {
+			wait = sub.next.Sub(now)
+			break
+		}
+
+		heap.Pop(&a.queue)
+		if a.hosts[sub.host] >= a.config.HostLimit {
+			// This will be retried once one of
+			// the host's updates finishes.
+			blocked = append(blocked, sub)
+			continue
+		}
+
+		a.inFlight++
+		a.hosts[sub.host]++
+		a.wg.Add(1)
+		go a.update(sub)
+	}
+
+	for _, sub := range blocked {
+		heap.Push(&a.queue, sub)
+	}
+
+	return wait
+}
+
+// update updates the feed for sub and delivers
+// any new items.
+func (a *Aggregator) update(sub *subscription) {
+	defer a.wg.Done()
+
+	f := sub.feed
+	changes, err := f.UpdateChanges(a.ctx)
+	f.mu.RLock()
+	url, refresh := f.UpdateURL, f.Refresh
+	f.mu.RUnlock()
+
+	a.mu.Lock()
+	a.inFlight--
+	if a.hosts[sub.host]--; a.hosts[sub.host] <= 0 {
+		delete(a.hosts, sub.host)
+	}
+	subscribed := a.subs[sub.url] == sub
+	gone := false
+	switch {
+	case !subscribed:
+		// The feed was removed while it was being
+		// updated, so the update may have saved it
+		// again.
+		gone = a.subs[url] == nil
+	case errors.Is(err, ErrGone):
+		// The feed has been removed for good.
+		delete(a.subs, sub.url)
+		gone = true
+	case url != sub.url && a.subs[url] != nil:
+		// The feed has moved to one we already
+		// have, so this one is no longer needed.
+		delete(a.subs, sub.url)
+		subscribed = false
+	default:
+		if url != sub.url {
+			// The feed has moved.
+			delete(a.subs, sub.url)
+			sub.url = url
+			sub.host = hostOf(url)
+			a.subs[sub.url] = sub
+		}
+		sub.next = refresh
+		if errors.Is(err, ErrTooSoon) {
+			err = nil
+		} else if err != nil && !sub.next.After(time.Now()) {
+			// Don't retry a failing feed straight away.
+			sub.next = time.Now().Add(DefaultRefreshInterval)
+		}
+		heap.Push(&a.queue, sub)
+	}
+	a.mu.Unlock()
+	a.signal()
+
+	if gone {
+		a.forget(f, url)
+	}
+	if !subscribed {
+		return
+	}
+	if err != nil && a.config.OnError != nil && a.ctx.Err() == nil {
+		a.config.OnError(f, err)
+	}
+
+	// If the feed was updated but could not be
+	// saved, the new items are still delivered.
+	if changes == nil || len(changes.added) == 0 {
+		return
+	}
+	items := changes.added
+	if a.config.OnItems != nil {
+		a.config.OnItems(f, items)
+		return
+	}
+
+	select {
+	case a.items <- NewItems{Feed: f, Items: items, Changes: changes}:
+	case <-a.ctx.Done():
+	}
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Host)
+}
+
+// subscriptionQueue is a heap of subscriptions,
+// ordered by their next update.
+type subscriptionQueue []*subscription
+
+func (q subscriptionQueue) Len() int { return len(q) }
+
+func (q subscriptionQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+
+func (q subscriptionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *subscriptionQueue) Push(x interface{}) {
+	sub := x.(*subscription)
+	sub.index = len(*q)
+	*q = append(*q, sub)
+}
+
+func (q *subscriptionQueue) Pop() interface{} {
+	old := *q
+	sub := old[len(old)-1]
+	old[len(old)-1] = nil
+	sub.index = -1
+	*q = old[:len(old)-1]
+	return sub
+}
@@ -0,0 +1,237 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// feedServer serves testdata files, recording
+// the most requests it handled at once.
+type feedServer struct {
+	*httptest.Server
+	delay time.Duration
+
+	mu       sync.Mutex
+	current  int
+	max      int
+	requests int
+}
+
+func newFeedServer(delay time.Duration) *feedServer {
+	s := &feedServer{delay: delay}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		s.current++
+		if s.current > s.max {
+			s.max = s.current
+		}
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.current--
+			s.mu.Unlock()
+		}()
+
+		select {
+		case <-time.After(s.delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		data, err := ioutil.ReadFile("testdata" + r.URL.Path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	return s
+}
+
+func (s *feedServer) feed(path string) *Feed {
+	return &Feed{UpdateURL: s.URL + path, RequestFunc: s.Client().Do}
+}
+
+func TestAggregator(t *testing.T) {
+	server := newFeedServer(0)
+	defer server.Close()
+
+	a := NewAggregator(AggregatorConfig{})
+	for _, path := range []string{"/rss_2.0", "/atom_1.0"} {
+		if err := a.Add(server.feed(path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Add(server.feed("/rss_2.0")); err == nil {
+		t.Errorf("Expected an error adding a feed twice")
+	}
+
+	got := make(map[string]int)
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case batch := <-a.Items():
+			got[batch.Feed.UpdateURL] += len(batch.Items)
+		case <-timeout:
+			t.Fatalf("Timed out waiting for items, got %v", got)
+		}
+	}
+
+	for _, f := range a.List() {
+		if got[f.UpdateURL] != len(f.Items) || len(f.Items) == 0 {
+			t.Errorf("%s: delivered %d items, feed has %d", f.UpdateURL, got[f.UpdateURL], len(f.Items))
+		}
+		if !f.Refresh.After(time.Now()) {
+			t.Errorf("%s: refresh was not scheduled", f.UpdateURL)
+		}
+	}
+
+	if !a.Remove(server.URL + "/atom_1.0") {
+		t.Errorf("Remove did not find the feed")
+	}
+	if a.Remove(server.URL + "/atom_1.0") {
+		t.Errorf("Remove found the feed twice")
+	}
+	if n := len(a.List()); n != 1 {
+		t.Errorf("got %d feeds after Remove, want 1", n)
+	}
+
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-a.Items(); ok {
+		t.Errorf("Items channel was not closed")
+	}
+	if err := a.Add(server.feed("/rss_2.0-1")); !errors.Is(err, ErrStopped) {
+		t.Errorf("got %v adding to a stopped aggregator, want ErrStopped", err)
+	}
+}
+
+func TestAggregatorLimits(t *testing.T) {
+	one := newFeedServer(50 * time.Millisecond)
+	defer one.Close()
+	two := newFeedServer(50 * time.Millisecond)
+	defer two.Close()
+
+	var mu sync.Mutex
+	updated := 0
+	a := NewAggregator(AggregatorConfig{
+		Workers:   3,
+		HostLimit: 2,
+		OnItems: func(f *Feed, items []*Item) {
+			mu.Lock()
+			updated++
+			mu.Unlock()
+		},
+	})
+
+	paths := []string{"/rss_2.0", "/rss_2.0-1", "/atom_1.0", "/atom_1.0-1", "/rss_2.0_enclosure"}
+	for _, path := range paths {
+		a.Add(one.feed(path))
+		a.Add(two.feed(path))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := updated
+		mu.Unlock()
+		if n == 2*len(paths) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out with %d of %d feeds updated", n, 2*len(paths))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	a.Stop(context.Background())
+
+	for name, server := range map[string]*feedServer{"one": one, "two": two} {
+		if server.max > 2 {
+			t.Errorf("server %s handled %d requests at once, want at most 2", name, server.max)
+		}
+	}
+	if one.max+two.max < 3 {
+		t.Errorf("servers handled at most %d and %d requests at once, want 3 in total", one.max, two.max)
+	}
+}
+
+func TestAggregatorErrors(t *testing.T) {
+	server := newFeedServer(0)
+	defer server.Close()
+
+	errs := make(chan error, 2)
+	a := NewAggregator(AggregatorConfig{
+		OnError: func(f *Feed, err error) { errs <- err },
+	})
+	a.Add(server.feed("/gone"))
+	a.Add(server.feed("/missing"))
+
+	var gone, missing bool
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			var status *HTTPStatusError
+			if !errors.As(err, &status) {
+				t.Fatalf("got %v, want *HTTPStatusError", err)
+			}
+			gone = gone || status.StatusCode == http.StatusGone
+			missing = missing || status.StatusCode == http.StatusNotFound
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for errors")
+		}
+	}
+	if !gone || !missing {
+		t.Errorf("got gone %v, missing %v", gone, missing)
+	}
+
+	// Gone feeds are dropped, others retried later.
+	list := a.List()
+	if len(list) != 1 || list[0].UpdateURL != server.URL+"/missing" {
+		t.Errorf("got %d feeds after errors, want only /missing", len(list))
+	}
+	a.Stop(context.Background())
+}
+
+func TestAggregatorStopTimeout(t *testing.T) {
+	server := newFeedServer(time.Minute)
+	defer server.Close()
+
+	a := NewAggregator(AggregatorConfig{})
+	a.Add(server.feed("/rss_2.0"))
+
+	// Wait for the update to start.
+	for i := 0; ; i++ {
+		server.mu.Lock()
+		started := server.requests > 0
+		server.mu.Unlock()
+		if started {
+			break
+		}
+		if i > 500 {
+			t.Fatal("Timed out waiting for the update to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := a.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Stop took %v", elapsed)
+	}
+}
@@ -1,25 +1,20 @@
 package rss
 
 import (
-	"bytes"
 	"encoding/xml"
 	"fmt"
 	"time"
 )
 
-func parseAtom(data []byte) (*Feed, error) {
-	warnings := false
-	feed := atomFeed{}
-	p := xml.NewDecoder(bytes.NewReader(data))
-	p.CharsetReader = charsetReader
-	err := p.Decode(&feed)
-	if err != nil {
-		return nil, err
-	}
-
+// Feed returns the feed's metadata. It does
+// not include any entries.
+func (feed *atomFeed) Feed() *Feed {
 	out := new(Feed)
 	out.Title = feed.Title
+	out.Language = feed.Language
+	out.Author = feed.Author.Name
 	out.Description = feed.Description
+	out.Categories = feed.Categories.toArray()
 	for _, link := range feed.Link {
 		if link.Rel == "alternate" || link.Rel == "" {
 			out.Link = link.Href
@@ -27,96 +22,234 @@ func parseAtom(data []byte) (*Feed, error) {
 		}
 	}
 	out.Image = feed.Image.Image()
-	out.Refresh = time.Now().Add(DefaultRefreshInterval)
-
-	out.Items = make([]*Item, 0, len(feed.Items))
-	out.ItemMap = make(map[string]struct{})
+	if out.Image.URL == "" {
+		out.Image.URL = feed.Logo
+	}
+	if out.Image.URL == "" {
+		out.Image.URL = feed.Icon
+	}
+	out.DublinCore = feed.dc.DublinCore()
+	if out.DublinCore != nil {
+		out.DublinCore.fillFeed(out)
+	}
+	out.Podcast = feed.itunes.Podcast()
+	if p := out.Podcast; p != nil {
+		if out.Author == "" {
+			out.Author = p.Author
+		}
+		if len(out.Categories) == 0 {
+			out.Categories = p.categoryNames()
+		}
+		if out.Image.URL == "" && out.Image.Href == "" {
+			out.Image.Href = p.Image
+		}
+	}
+	out.PodcastIndex = feed.podcastIndex.PodcastIndex()
+	out.Refresh = feed.next(time.Now())
 
-	// Process items.
-	for _, item := range feed.Items {
+	return out
+}
 
-		// Skip items already known.
-		if _, ok := out.ItemMap[item.ID]; ok {
-			continue
+// Item converts item, returning nil if it
+// has no ID.
+func (item *atomItem) Item() *Item {
+	var err error
+	next := new(Item)
+	next.Title = item.Title
+	next.Summary = item.Summary
+	next.Content = item.Content.String()
+	next.Categories = item.Categories.toArray()
+	next.Author = item.Author.Name
+	next.Podcast = item.itunes.Podcast()
+	if p := next.Podcast; p != nil {
+		if next.Title == "" {
+			next.Title = p.Title
 		}
-
-		next := new(Item)
-		next.Title = item.Title
-		next.Summary = item.Summary
-		next.Content = item.Content.RAWContent
-		if item.Date != "" {
-			next.Date, err = parseTime(item.Date)
-			if err == nil {
-				next.DateValid = true
-			}
+		if next.Summary == "" {
+			next.Summary = p.Summary
 		}
-		next.ID = item.ID
-		for _, link := range item.Links {
-			if link.Rel == "alternate" || link.Rel == "" {
-				next.Link = link.Href
-			} else {
-				next.Enclosures = append(next.Enclosures, &Enclosure{
-					URL:    link.Href,
-					Type:   link.Type,
-					Length: link.Length,
-				})
-			}
+		if next.Author == "" {
+			next.Author = p.Author
 		}
-		next.Read = false
-
-		if next.ID == "" {
-			if debug {
-				fmt.Printf("[w] Item %q has no ID and will be ignored.\n", next.Title)
-				fmt.Printf("[w] %#v\n", item)
-			}
-			warnings = true
-			continue
+		if p.Image != "" {
+			next.Image = &Image{Href: p.Image}
 		}
-
-		if _, ok := out.ItemMap[next.ID]; ok {
-			if debug {
-				fmt.Printf("[w] Item %q has duplicate ID.\n", next.Title)
-				fmt.Printf("[w] %#v\n", next)
-			}
-			warnings = true
-			continue
+	}
+	next.PodcastIndex = item.podcastIndex.PodcastIndex()
+	next.Media = item.media.Media()
+	if next.Media != nil {
+		next.Media.fill(next)
+	}
+	if item.Date != "" {
+		next.Date, err = parseTime(item.Date)
+		if err == nil {
+			next.DateValid = true
+		}
+	}
+	next.DublinCore = item.dc.DublinCore()
+	if next.DublinCore != nil {
+		next.DublinCore.fillItem(next)
+	}
+	next.ID = item.ID
+	for _, link := range item.Links {
+		if link.Rel == "alternate" || link.Rel == "" {
+			next.Link = link.Href
+		} else {
+			next.Enclosures = append(next.Enclosures, &Enclosure{
+				URL:    link.Href,
+				Type:   link.Type,
+				Length: link.Length,
+			})
 		}
-
-		out.Items = append(out.Items, next)
-		out.ItemMap[next.ID] = struct{}{}
-		out.Unread++
 	}
+	next.Read = false
 
-	if warnings && debug {
-		fmt.Printf("[i] Encountered warnings:\n%s\n", data)
+	if next.ID == "" {
+		if debug {
+			fmt.Printf("[w] Item %q has no ID and will be ignored.\n", next.Title)
+			fmt.Printf("[w] %#v\n", item)
+		}
+		return nil
 	}
 
-	return out, nil
+	return next
 }
 
 type RAWContent struct {
 	RAWContent string `xml:",innerxml"`
+	Type       string `xml:"type,attr"`
+	Chardata   string `xml:",chardata"`
+	Elements   []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// String returns the content's text. Markup is
+// returned verbatim for XHTML content, while
+// escaped HTML and text are unescaped.
+func (r *RAWContent) String() string {
+	if r.Type == "xhtml" || len(r.Elements) > 0 {
+		return r.RAWContent
+	}
+	return r.Chardata
 }
 
 type atomFeed struct {
-	XMLName     xml.Name   `xml:"feed"`
-	Title       string     `xml:"title"`
-	Description string     `xml:"subtitle"`
-	Link        []atomLink `xml:"link"`
-	Image       atomImage  `xml:"image"`
-	Items       []atomItem `xml:"entry"`
-	Updated     string     `xml:"updated"`
+	XMLName     xml.Name          `xml:"feed"`
+	Language    string            `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title       string            `xml:"title"`
+	Author      atomAuthor        `xml:"author"`
+	Description string            `xml:"subtitle"`
+	Categories  atomCategorySlice `xml:"category"`
+	Link        []atomLink        `xml:"link"`
+	Image       atomImage         `xml:"image"`
+	Logo        string            `xml:"logo"`
+	Icon        string            `xml:"icon"`
+	Items       []atomItem        `xml:"entry"`
+	Updated     string            `xml:"updated"`
+	syndication
+
+	itunes       itunesElements
+	podcastIndex podcastIndexElements
+	dc           dublinCoreElements
+}
+
+// isCore implements coreElements. Only elements
+// in the Atom namespaces are taken, or with no
+// namespace in feeds that have none, along with
+// the Syndication module's.
+func (feed *atomFeed) isCore(name xml.Name) bool {
+	return isAtomCore(name) || name.Space == nsSyndication
+}
+
+// isCore implements coreElements, like the
+// feed's.
+func (item *atomItem) isCore(name xml.Name) bool {
+	return isAtomCore(name)
+}
+
+// isAtomCore reports whether the element with the
+// given name is in an Atom namespace.
+func isAtomCore(name xml.Name) bool {
+	switch name.Space {
+	case nsAtom, nsAtom03, "":
+		return true
+	}
+	return false
+}
+
+// extensions returns the values into which the
+// feed's elements in extension namespaces are
+// decoded.
+func (feed *atomFeed) extensions() map[string]interface{} {
+	ext := make(map[string]interface{})
+	feed.itunes.register(ext)
+	feed.podcastIndex.register(ext)
+	feed.dc.register(ext)
+	return ext
 }
 
 type atomItem struct {
-	XMLName   xml.Name   `xml:"entry"`
-	Title     string     `xml:"title"`
-	Summary   string     `xml:"summary"`
-	Content   RAWContent `xml:"content"`
-	Links     []atomLink `xml:"link"`
-	Date      string     `xml:"updated"`
-	DateValid bool
-	ID        string `xml:"id"`
+	XMLName    xml.Name          `xml:"entry"`
+	Title      string            `xml:"title"`
+	Author     atomAuthor        `xml:"author"`
+	Summary    string            `xml:"summary"`
+	Content    RAWContent        `xml:"content"`
+	Categories atomCategorySlice `xml:"category"`
+	Links      []atomLink        `xml:"link"`
+	Date       string            `xml:"updated"`
+	DateValid  bool
+	ID         string `xml:"id"`
+
+	itunes       itunesElements
+	podcastIndex podcastIndexElements
+	media        mediaElements
+	dc           dublinCoreElements
+}
+
+// rawChild implements rawChildren, as the
+// content is kept as raw XML.
+func (item *atomItem) rawChild(name xml.Name) interface{} {
+	if name.Local == "content" {
+		return &item.Content
+	}
+	return nil
+}
+
+// extensions returns the values into which the
+// entry's elements in extension namespaces are
+// decoded.
+func (item *atomItem) extensions() map[string]interface{} {
+	ext := make(map[string]interface{})
+	item.itunes.register(ext)
+	item.podcastIndex.register(ext)
+	item.media.register(ext)
+	item.dc.register(ext)
+	return ext
+}
+
+type atomAuthor struct {
+	Name  string `xml:"name"`
+	URI   string `xml:"uri,omitempty"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomCategorySlice []atomCategory
+
+func (a atomCategorySlice) toArray() (result []string) {
+	if len(a) == 0 {
+		return
+	}
+	result = make([]string, len(a))
+	for i := range a {
+		result[i] = a[i].Term
+	}
+	return
 }
 
 type atomImage struct {
@@ -129,9 +262,9 @@ type atomImage struct {
 
 type atomLink struct {
 	Href   string `xml:"href,attr"`
-	Rel    string `xml:"rel,attr"`
-	Type   string `xml:"type,attr"`
-	Length uint   `xml:"length,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length uint   `xml:"length,attr,omitempty"`
 }
 
 func (a *atomImage) Image() *Image {
@@ -60,3 +60,26 @@ func TestParseAtomContent(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAtomContentTypes(t *testing.T) {
+	// Text and escaped HTML are unescaped, so that
+	// they round-trip through WriteAtom, while XHTML
+	// is kept as markup.
+	tests := map[string]string{
+		`<content>1 &lt; 2</content>`: "1 < 2",
+		`<content type="html">&lt;p&gt;Some &lt;b&gt;bold&lt;/b&gt; text&lt;/p&gt;</content>`:          "<p>Some <b>bold</b> text</p>",
+		`<content type="html"><![CDATA[<p>CDATA</p>]]></content>`:                                      "<p>CDATA</p>",
+		`<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>XHTML</p></div></content>`: `<div xmlns="http://www.w3.org/1999/xhtml"><p>XHTML</p></div>`,
+		`<content><p>Untyped markup</p></content>`:                                                     "<p>Untyped markup</p>",
+	}
+
+	for content, want := range tests {
+		feed, err := Parse([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>1</id>` + content + `</entry></feed>`))
+		if err != nil {
+			t.Fatalf("Parsing %s: %v", content, err)
+		}
+		if got := feed.Items[0].Content; got != want {
+			t.Errorf("%s: got %q, want %q", content, got, want)
+		}
+	}
+}
@@ -0,0 +1,700 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ParseReader reads RSS, Atom or JSON Feed data
+// from r. Unlike Parse, it does not need the whole
+// document in memory at once, although the items
+// in the returned Feed are, of course, kept.
+func ParseReader(r io.Reader) (*Feed, error) {
+	out, err := parseReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	out.setDefaultRefresh(time.Now())
+
+	return out, nil
+}
+
+// parseReader is like ParseReader, but leaves
+// Refresh zero if the feed does not set it.
+func parseReader(r io.Reader) (*Feed, error) {
+	d := NewDecoder(r)
+
+	var items []*Item
+	for {
+		item, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	out := d.feed()
+	out.addItems(items)
+
+	return out, nil
+}
+
+// A Decoder reads a feed from an input stream,
+// returning its items one at a time. Only the
+// item being decoded and the feed's metadata
+// are held in memory, so feeds with very many
+// items can be processed in constant space.
+//
+// Example usage:
+//
+//	d := rss.NewDecoder(r)
+//	for {
+//		item, err := d.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			// handle error.
+//		}
+//
+//		// ... use item ...
+//	}
+//
+//	feed := d.Feed()
+//
+// Unlike Parse, a Decoder does not remove items
+// with duplicate IDs.
+type Decoder struct {
+	rec     *recorder
+	r       *bufio.Reader
+	skipped int64 // Bytes read before dec was started.
+	dec     feedDecoder
+	format  Format
+	err     error
+}
+
+// feedDecoder is implemented by the decoder
+// for each feed format.
+type feedDecoder interface {
+	next() (*Item, error)
+	feed() *Feed
+	offset() int64
+}
+
+// NewDecoder returns a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	rec := &recorder{r: r}
+	return &Decoder{rec: rec, r: bufio.NewReader(rec)}
+}
+
+// Next returns the next item in the feed. It
+// returns io.EOF once the end of the feed has
+// been reached. Items that cannot be identified
+// are skipped.
+//
+// If the feed is malformed, the error is a
+// *ParseError. Errors from the underlying reader
+// are returned as they are.
+func (d *Decoder) Next() (*Item, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if d.dec == nil {
+		d.dec, d.err = d.start()
+		if d.err != nil {
+			d.err = d.rec.parseError(d.format, d.skipped, d.err)
+			return nil, d.err
+		}
+		if debug {
+			fmt.Printf("[i] Parsing as %s\n", d.format)
+		}
+	}
+
+	item, err := d.dec.next()
+	if err == io.EOF {
+		d.err = err
+	} else if err != nil {
+		d.err = d.rec.parseError(d.format, d.skipped+d.dec.offset(), err)
+		return nil, d.err
+	}
+
+	return item, err
+}
+
+// Feed returns the feed's metadata, such as its
+// title and image. Metadata can appear after the
+// items, so Feed is only complete once Next has
+// returned io.EOF. The returned Feed contains no
+// items.
+func (d *Decoder) Feed() *Feed {
+	out := d.feed()
+	out.setDefaultRefresh(time.Now())
+	return out
+}
+
+func (d *Decoder) feed() *Feed {
+	if d.dec == nil {
+		return new(Feed)
+	}
+
+	out := d.dec.feed()
+	out.Format = d.format
+	return out
+}
+
+// Format returns the format of the feed. It is
+// only known once Next has been called.
+func (d *Decoder) Format() Format {
+	return d.format
+}
+
+// Namespaces used to identify feed formats.
+const (
+	nsRDF    = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsAtom   = "http://www.w3.org/2005/Atom"
+	nsAtom03 = "http://purl.org/atom/ns#"
+)
+
+// start determines the feed's format from
+// the root element, skipping any byte order
+// mark, prolog, comments and processing
+// instructions, and returns the decoder for
+// the format.
+func (d *Decoder) start() (feedDecoder, error) {
+	// Skip any byte order mark and leading
+	// space to see whether this is JSON.
+	if bom, err := d.r.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		d.r.Discard(3)
+		d.skipped += 3
+	}
+	for {
+		b, err := d.r.ReadByte()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: empty document", ErrUnknownFormat)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			d.skipped++
+			continue
+		}
+		d.r.UnreadByte()
+		if b == '{' {
+			d.format = FormatJSON
+			return newJSONDecoder(d.r), nil
+		}
+		break
+	}
+
+	x := xml.NewDecoder(d.r)
+	x.CharsetReader = charsetReader
+	for {
+		tok, err := x.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: no root element", ErrUnknownFormat)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		root, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case root.Name.Local == "rss":
+			d.format = FormatRSS2
+			return newRSS2Decoder(x, root), nil
+		case root.Name.Local == "RDF" && root.Name.Space == nsRDF:
+			d.format = FormatRSS1
+			return newRSS1Decoder(x, root), nil
+		case root.Name.Local == "feed" && (root.Name.Space == nsAtom || root.Name.Space == nsAtom03 || root.Name.Space == ""):
+			d.format = FormatAtom
+			return newAtomDecoder(x, root), nil
+		default:
+			d.skipped += x.InputOffset()
+			return nil, fmt.Errorf("%w: root element <%s> in namespace %q", ErrUnknownFormat, root.Name.Local, root.Name.Space)
+		}
+	}
+}
+
+// xmlDecoder walks an XML feed, decoding item
+// elements one at a time. Other children of the
+// item container are decoded into the feed's
+// metadata.
+type xmlDecoder struct {
+	d         *xml.Decoder
+	container xml.StartElement // Parent of the items.
+	name      string           // Name of the container.
+	inside    bool             // Whether we are in the container.
+	found     bool             // Whether the container has been seen.
+	itemName  string
+
+	// item decodes an item element.
+	item func(start *xml.StartElement) (*Item, error)
+
+	// meta is decoded from the container
+	// element, without its items.
+	meta interface{}
+
+	// ext holds the values into which children
+	// of the container in other namespaces are
+	// decoded instead of meta, by namespace.
+	ext map[string]interface{}
+
+	// convert returns the Feed for meta.
+	convert func() *Feed
+
+	// check reports whether meta is complete,
+	// once the document has been read.
+	check func() error
+}
+
+// newXMLDecoder returns an xmlDecoder for a feed
+// whose items are direct children of the root.
+func newXMLDecoder(d *xml.Decoder, root xml.StartElement, itemName string) *xmlDecoder {
+	return &xmlDecoder{
+		d:         d,
+		container: root,
+		name:      root.Name.Local,
+		inside:    true,
+		found:     true,
+		itemName:  itemName,
+	}
+}
+
+func (x *xmlDecoder) next() (*Item, error) {
+	for {
+		tok, err := x.d.Token()
+		if err == io.EOF {
+			if x.check != nil {
+				if err := x.check(); err != nil {
+					return nil, err
+				}
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !x.inside {
+				if t.Name.Local != x.name {
+					if err := x.d.Skip(); err != nil {
+						return nil, err
+					}
+					continue
+				}
+
+				x.container = t.Copy()
+				x.inside = true
+				x.found = true
+				continue
+			}
+
+			if t.Name.Local == x.itemName {
+				item, err := x.item(&t)
+				if err != nil {
+					return nil, err
+				}
+				if item == nil {
+					continue
+				}
+				return item, nil
+			}
+
+			if err := x.decodeMeta(&t); err != nil {
+				return nil, err
+			}
+
+		case xml.EndElement:
+			if x.inside && t.Name == x.container.Name {
+				x.inside = false
+			}
+		}
+	}
+}
+
+func (x *xmlDecoder) feed() *Feed {
+	return x.convert()
+}
+
+func (x *xmlDecoder) offset() int64 {
+	return x.d.InputOffset()
+}
+
+// decodeMeta decodes the child element start of
+// the container into x.meta, or into x.ext for
+// its namespace. Children that x.meta does not
+// take (see coreElements) are skipped.
+func (x *xmlDecoder) decodeMeta(start *xml.StartElement) error {
+	v := x.meta
+	if ext, ok := x.ext[start.Name.Space]; ok {
+		v = ext
+	} else if core, ok := v.(coreElements); ok && !core.isCore(start.Name) {
+		return x.d.Skip()
+	}
+	r := &childReader{d: x.d, container: x.container, child: start.Copy()}
+	return xml.NewTokenDecoder(r).Decode(v)
+}
+
+// decodeElement is like d.DecodeElement, but
+// decodes children of start in the namespaces
+// in ext into ext's values instead of v. This
+// keeps extension elements, such as itunes:title,
+// from being mistaken for the element with the
+// same local name in v.
+// Children that v does not take are skipped
+// (see coreElements).
+func decodeElement(d *xml.Decoder, start *xml.StartElement, v interface{}, ext map[string]interface{}) error {
+	container := start.Copy()
+	empty := &tokenList{tokens: []xml.Token{container, container.End()}}
+	if err := xml.NewTokenDecoder(empty).Decode(v); err != nil {
+		return err
+	}
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			target, isExt := ext[t.Name.Space]
+			if !isExt {
+				if core, ok := v.(coreElements); ok && !core.isCore(t.Name) {
+					if err := d.Skip(); err != nil {
+						return err
+					}
+					continue
+				}
+				target = v
+				if raw, ok := v.(rawChildren); ok {
+					if field := raw.rawChild(t.Name); field != nil {
+						if err := d.DecodeElement(field, &t); err != nil {
+							return err
+						}
+						continue
+					}
+				}
+			}
+			r := &childReader{d: d, container: container, child: t.Copy()}
+			if err := xml.NewTokenDecoder(r).Decode(target); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// coreElements is implemented by values passed to
+// decodeElement, and by an xmlDecoder's meta, whose
+// fields belong to certain namespaces. Children in
+// other namespaces that are not in ext are skipped,
+// so that an element such as googleplay:author is
+// not taken for the one with the same local name.
+type coreElements interface {
+	// isCore reports whether the child with the
+	// given name is decoded into the value.
+	isCore(name xml.Name) bool
+}
+
+// rawChildren is implemented by values passed to
+// decodeElement that have fields needing the raw
+// XML of a child, such as innerxml fields, which
+// decoding from tokens cannot fill.
+type rawChildren interface {
+	// rawChild returns the field into which the
+	// child with the given name is decoded, or
+	// nil if it can be decoded as usual.
+	rawChild(name xml.Name) interface{}
+}
+
+// tokenList is an xml.TokenReader that returns
+// a fixed list of tokens.
+type tokenList struct {
+	tokens []xml.Token
+}
+
+func (l *tokenList) Token() (xml.Token, error) {
+	if len(l.tokens) == 0 {
+		return nil, io.EOF
+	}
+	tok := l.tokens[0]
+	l.tokens = l.tokens[1:]
+	return tok, nil
+}
+
+// childReader presents a single child element as
+// the only content of its parent element, so that
+// it can be decoded into the parent's structure.
+type childReader struct {
+	d         *xml.Decoder
+	container xml.StartElement
+	child     xml.StartElement
+	state     int
+	depth     int
+}
+
+func (c *childReader) Token() (xml.Token, error) {
+	switch c.state {
+	case 0:
+		c.state++
+		return c.container, nil
+	case 1:
+		c.state++
+		c.depth = 1
+		return c.child, nil
+	case 2:
+		tok, err := c.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			c.depth++
+		case xml.EndElement:
+			c.depth--
+			if c.depth == 0 {
+				c.state++
+			}
+		}
+		return xml.CopyToken(tok), nil
+	case 3:
+		c.state++
+		return c.container.End(), nil
+	}
+
+	return nil, io.EOF
+}
+
+func newRSS2Decoder(d *xml.Decoder, root xml.StartElement) feedDecoder {
+	channel := new(rss2_0Channel)
+	x := &xmlDecoder{
+		d:        d,
+		name:     "channel",
+		itemName: "item",
+		item: func(start *xml.StartElement) (*Item, error) {
+			var item rss2_0Item
+			if err := decodeElement(d, start, &item, item.extensions()); err != nil {
+				return nil, err
+			}
+			return item.Item(), nil
+		},
+	}
+	x.meta = channel
+	x.ext = channel.extensions()
+	x.convert = channel.Feed
+	x.check = func() error {
+		if !x.found {
+			return errors.New("no channel found")
+		}
+		return nil
+	}
+
+	return x
+}
+
+func newRSS1Decoder(d *xml.Decoder, root xml.StartElement) feedDecoder {
+	feed := new(rss1_0Feed)
+	x := newXMLDecoder(d, root, "item")
+	x.item = func(start *xml.StartElement) (*Item, error) {
+		var item rss1_0Item
+		if err := decodeElement(d, start, &item, item.extensions()); err != nil {
+			return nil, err
+		}
+		return item.Item(), nil
+	}
+	x.meta = feed
+	x.convert = func() *Feed {
+		if feed.Channel == nil {
+			return new(Feed)
+		}
+		return feed.Channel.Feed()
+	}
+	x.check = func() error {
+		if feed.Channel == nil {
+			return errors.New("no channel found")
+		}
+		return nil
+	}
+
+	return x
+}
+
+func newAtomDecoder(d *xml.Decoder, root xml.StartElement) feedDecoder {
+	feed := new(atomFeed)
+	x := newXMLDecoder(d, root, "entry")
+	x.item = func(start *xml.StartElement) (*Item, error) {
+		var item atomItem
+		if err := decodeElement(d, start, &item, item.extensions()); err != nil {
+			return nil, err
+		}
+		return item.Item(), nil
+	}
+	x.meta = feed
+	x.ext = feed.extensions()
+	x.convert = feed.Feed
+
+	return x
+}
+
+// jsonDecoder walks a JSON Feed object, decoding
+// items one at a time. Other members are kept
+// and decoded into the feed's metadata.
+type jsonDecoder struct {
+	d       *json.Decoder
+	fields  map[string]json.RawMessage
+	started bool
+	inItems bool
+	done    bool
+}
+
+func newJSONDecoder(r io.Reader) feedDecoder {
+	return &jsonDecoder{
+		d:      json.NewDecoder(r),
+		fields: make(map[string]json.RawMessage),
+	}
+}
+
+func (j *jsonDecoder) next() (*Item, error) {
+	if j.done {
+		return nil, io.EOF
+	}
+
+	if !j.started {
+		if err := j.expect(json.Delim('{')); err != nil {
+			return nil, err
+		}
+		j.started = true
+	}
+
+	for {
+		if j.inItems {
+			if !j.d.More() {
+				if err := j.expect(json.Delim(']')); err != nil {
+					return nil, err
+				}
+				j.inItems = false
+				continue
+			}
+
+			var item jsonItem
+			if err := j.d.Decode(&item); err != nil {
+				return nil, err
+			}
+			if next := item.Item(); next != nil {
+				return next, nil
+			}
+			continue
+		}
+
+		if !j.d.More() {
+			if err := j.expect(json.Delim('}')); err != nil {
+				return nil, err
+			}
+			j.done = true
+			if err := j.meta().checkVersion(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+
+		tok, err := j.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %v in JSON Feed", tok)
+		}
+
+		if key == "items" {
+			tok, err := j.d.Token()
+			if err != nil {
+				return nil, err
+			}
+			if tok == json.Delim('[') {
+				j.inItems = true
+			} else if tok != nil {
+				return nil, fmt.Errorf("unexpected %v for JSON Feed items", tok)
+			}
+			continue
+		}
+
+		var value json.RawMessage
+		if err := j.d.Decode(&value); err != nil {
+			return nil, err
+		}
+		j.fields[key] = value
+
+		if key == "version" {
+			if err := j.meta().checkVersion(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// expect reads the next token, which must be want.
+func (j *jsonDecoder) expect(want json.Delim) error {
+	tok, err := j.d.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("unexpected %v in JSON Feed, want %v", tok, want)
+	}
+	return nil
+}
+
+// meta decodes the members seen so far.
+func (j *jsonDecoder) meta() *jsonFeed {
+	// The object is rebuilt by hand, as
+	// json.Marshal would compact the values.
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for key, value := range j.fields {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	feed := new(jsonFeed)
+	json.Unmarshal(buf.Bytes(), feed)
+	return feed
+}
+
+func (j *jsonDecoder) offset() int64 {
+	return j.d.InputOffset()
+}
+
+func (j *jsonDecoder) feed() *Feed {
+	return j.meta().Feed()
+}
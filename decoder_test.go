@@ -0,0 +1,227 @@
+package rss
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestParseReader(t *testing.T) {
+	tests := []struct {
+		file   string
+		format Format
+		title  string
+		link   string
+		items  int
+		id     string // Of the first item.
+		item   string // Title of the first item.
+	}{
+		{"rss_1.0_syndication", FormatRSS1, "Meerkat", "http://meerkat.oreillynet.com", 1, "http://c.moreover.com/click/here.pl?r123", "XML: A Disruptive Technology"},
+		{"rss_2.0", FormatRSS2, "RSS Title", "http://www.someexamplerssdomain.com/main.html", 2, "unique string per item", "Example entry"},
+		{"atom_1.0", FormatAtom, "Titel des Weblogs", "", 1, "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a", "Titel des Weblog-Eintrags"},
+		{"json_feed_1.1", FormatJSON, "My Example Feed", "https://example.org/", 2, "2", "Second item"},
+	}
+
+	for _, tt := range tests {
+		name := filepath.Join("testdata", tt.file)
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("Opening %s: %v", name, err)
+		}
+
+		// Read a byte at a time, so that nothing
+		// relies on having the whole document.
+		feed, err := ParseReader(iotest.OneByteReader(f))
+		f.Close()
+		if err != nil {
+			t.Fatalf("Parsing %s from reader: %v", name, err)
+		}
+
+		assertEqual(string(tt.format), string(feed.Format), t)
+		assertEqual(tt.title, feed.Title, t)
+		assertEqual(tt.link, feed.Link, t)
+		if len(feed.Items) != tt.items || feed.Unread != uint32(tt.items) {
+			t.Errorf("%s: got %d items and %d unread, want %d", name, len(feed.Items), feed.Unread, tt.items)
+			continue
+		}
+		assertEqual(tt.id, feed.Items[0].ID, t)
+		assertEqual(tt.item, feed.Items[0].Title, t)
+		if !feed.Refresh.After(time.Now()) {
+			t.Errorf("%s: got refresh %v, want a default in the future", name, feed.Refresh)
+		}
+	}
+}
+
+func TestDecoderMetadataAfterItems(t *testing.T) {
+	tests := map[string]string{
+		"RSS 2.0": `<rss version="2.0"><channel>
+			<item><guid>1</guid></item>
+			<item><guid>2</guid></item>
+			<title>Late title</title>
+		</channel></rss>`,
+		"RSS 1.0": `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+			<item><link>http://example.com/1</link></item>
+			<item><link>http://example.com/2</link></item>
+			<channel><title>Late title</title></channel>
+		</rdf:RDF>`,
+		"Atom": `<feed xmlns="http://www.w3.org/2005/Atom">
+			<entry><id>1</id></entry>
+			<entry><id>2</id></entry>
+			<title>Late title</title>
+		</feed>`,
+		"JSON Feed": `{
+			"items": [{"id": "1"}, {"id": "2"}],
+			"title": "Late title",
+			"version": "https://jsonfeed.org/version/1.1"
+		}`,
+	}
+
+	for format, data := range tests {
+		d := NewDecoder(strings.NewReader(data))
+		n := 0
+		for {
+			_, err := d.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			n++
+		}
+
+		if n != 2 {
+			t.Errorf("%s: got %d items, want 2", format, n)
+		}
+		if title := d.Feed().Title; title != "Late title" {
+			t.Errorf("%s: got title %q, want %q", format, title, "Late title")
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty":           "",
+		"unknown root":    "<html><body>Not a feed</body></html>",
+		"no channel":      `<rss version="2.0"></rss>`,
+		"truncated":       `<rss version="2.0"><channel><item><guid>1</guid></item>`,
+		"bad JSON":        `{"version": "https://jsonfeed.org/version/1.1", "items": [`,
+		"no JSON version": `{"items": []}`,
+	}
+
+	for name, data := range tests {
+		_, err := ParseReader(strings.NewReader(data))
+		if err == nil {
+			t.Errorf("%s: expected error, got none", name)
+		}
+	}
+}
+
+// itemGenerator is an io.Reader that produces an
+// RSS 2.0 feed with n items, without holding the
+// whole document in memory.
+type itemGenerator struct {
+	n, next int
+	buf     bytes.Buffer
+	read    int
+}
+
+func (g *itemGenerator) Read(p []byte) (int, error) {
+	for g.buf.Len() < len(p) && g.next <= g.n {
+		switch {
+		case g.next == 0:
+			g.buf.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>Generated</title>`)
+		case g.next == g.n:
+			g.buf.WriteString(`</channel></rss>`)
+		default:
+			fmt.Fprintf(&g.buf, "<item><guid>%d</guid><title>Item %d</title><description>%s</description></item>", g.next, g.next, strings.Repeat("x", 100))
+		}
+		g.next++
+	}
+	if g.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	n, err := g.buf.Read(p)
+	g.read += n
+	return n, err
+}
+
+func TestDecoderStreams(t *testing.T) {
+	const items = 50000
+	g := &itemGenerator{n: items + 1}
+	d := NewDecoder(g)
+
+	item, err := d.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual("1", item.ID, t)
+
+	if g.read > 64<<10 {
+		t.Errorf("Decoder read %d bytes to return the first item", g.read)
+	}
+
+	n := 1
+	for {
+		item, err = d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+
+	if n != items {
+		t.Errorf("got %d items, want %d", n, items)
+	}
+	assertEqual("Generated", d.Feed().Title, t)
+}
+
+func TestParseForeignNamespaces(t *testing.T) {
+	// Elements in namespaces the parser does not
+	// know do not replace the standard elements
+	// with the same local names.
+	data, err := ioutil.ReadFile("testdata/rss_2.0_googleplay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual("Garden Talk", feed.Title, t)
+	assertEqual("A weekly show about gardening.", feed.Description, t)
+	assertEqual("", feed.Author, t)
+	assertEqual("https://garden.example.com/", feed.Link, t)
+	if feed.Image != nil && feed.Image.URL != "" {
+		t.Errorf("got image %+v from googleplay:image", feed.Image)
+	}
+
+	item := feed.Items[0]
+	assertEqual("Planting Bulbs", item.Title, t)
+	assertEqual("When and how to plant bulbs.", item.Summary, t)
+	assertEqual("jo@garden.example.com (Jo)", item.Author, t)
+	assertEqual("<p>Plant them in autumn.</p>", item.Content, t)
+	if len(item.Enclosures) != 1 {
+		t.Errorf("got %d enclosures, want 1", len(item.Enclosures))
+	}
+
+	atom := mustParse(t, `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:googleplay="http://www.google.com/schemas/play-podcasts/1.0">
+		<title>Atom</title><googleplay:title>Not the title</googleplay:title>
+		<entry><id>1</id><title>Entry</title><googleplay:summary>Not the summary</googleplay:summary><summary>Summary</summary></entry>
+	</feed>`)
+	assertEqual("Atom", atom.Title, t)
+	assertEqual("Entry", atom.Items[0].Title, t)
+	assertEqual("Summary", atom.Items[0].Summary, t)
+}
@@ -0,0 +1,234 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// A Candidate is a feed found by Discover.
+type Candidate struct {
+	URL    string
+	Title  string
+	Format Format // Empty if the page did not say.
+}
+
+// maxPageSize is the most of a web page that
+// Discover reads.
+const maxPageSize = 2 << 20
+
+// fallbackPaths are the paths, relative to a
+// site's root, where Discover looks for feeds if
+// a page does not link to any.
+var fallbackPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml"}
+
+// feedTypes maps the media types used to link
+// to feeds to their formats.
+var feedTypes = map[string]Format{
+	"application/rss+xml":   FormatRSS2,
+	"application/rdf+xml":   FormatRSS1,
+	"application/atom+xml":  FormatAtom,
+	"application/feed+json": FormatJSON,
+	"application/xml":       "",
+	"text/xml":              "",
+}
+
+// Discover finds the feeds for the web page at
+// pageURL, such as a site's home page, ranked
+// with the most likely first.
+//
+// The feeds are taken from the page's <link
+// rel="alternate"> tags. If there are none, some
+// common locations, such as /feed and /rss.xml,
+// are tried instead. If pageURL is itself a feed,
+// it is the only candidate. Finding no feeds is
+// not an error.
+func Discover(ctx context.Context, pageURL string) ([]*Candidate, error) {
+	return DiscoverByRequestFunc(ctx, DefaultRequestFunc, pageURL)
+}
+
+// DiscoverByFunc is like Discover, but uses a
+// func to fetch each URL.
+func DiscoverByFunc(ctx context.Context, fetchFunc FetchFunc, pageURL string) ([]*Candidate, error) {
+	return DiscoverByRequestFunc(ctx, func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return fetchFunc(req.URL.String())
+	}, pageURL)
+}
+
+// DiscoverByRequestFunc is like Discover, but uses
+// a func to perform each request.
+func DiscoverByRequestFunc(ctx context.Context, requestFunc RequestFunc, pageURL string) ([]*Candidate, error) {
+	page, base, contentType, err := fetchPage(ctx, requestFunc, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if f, err := parseReader(bytes.NewReader(page)); err == nil {
+		return []*Candidate{{URL: pageURL, Title: f.Title, Format: f.Format}}, nil
+	}
+
+	candidates := findFeedLinks(page, base, contentType)
+	if len(candidates) > 0 {
+		rankCandidates(candidates)
+		return candidates, nil
+	}
+
+	for _, path := range fallbackPaths {
+		ref, _ := url.Parse(path)
+		u := base.ResolveReference(ref).String()
+		f, err := fetchByRequestFunc(ctx, requestFunc, u, "", "")
+		if err == nil {
+			candidates = append(candidates, &Candidate{URL: u, Title: f.Title, Format: f.Format})
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return candidates, nil
+}
+
+// fetchPage returns the start of the page at
+// pageURL, the URL it was fetched from in the
+// end, and its media type.
+func fetchPage(ctx context.Context, requestFunc RequestFunc, pageURL string) ([]byte, *url.URL, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	resp, err := requestFunc(req)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return nil, nil, "", &HTTPStatusError{
+			URL:        pageURL,
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+		}
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	base := req.URL
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	return page, base, resp.Header.Get("Content-Type"), nil
+}
+
+// findFeedLinks returns the feeds linked from
+// the HTML page, resolved against base.
+//
+// The page is read with an HTML tokenizer, which
+// copes with unquoted attributes and with markup
+// in scripts. As the links belong in the page's
+// head, reading stops at the body.
+func findFeedLinks(page []byte, base *url.URL, contentType string) []*Candidate {
+	var input io.Reader = bytes.NewReader(page)
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		if r, err := charsetReader(params["charset"], input); err == nil {
+			input = r
+		}
+	}
+
+	z := html.NewTokenizer(input)
+
+	var candidates []*Candidate
+	seen := make(map[string]bool)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return candidates
+		case html.StartTagToken, html.SelfClosingTagToken:
+		default:
+			continue
+		}
+
+		name, hasAttr := z.TagName()
+		attrs := make(map[string]string)
+		for hasAttr {
+			var key, value []byte
+			key, value, hasAttr = z.TagAttr()
+			attrs[string(key)] = strings.TrimSpace(string(value))
+		}
+
+		switch string(name) {
+		case "body":
+			return candidates
+		case "base":
+			if ref, err := url.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+				base = base.ResolveReference(ref)
+			}
+		case "link":
+			if !hasToken(attrs["rel"], "alternate") {
+				continue
+			}
+			mediaType, _, _ := mime.ParseMediaType(attrs["type"])
+			format, ok := feedTypes[strings.ToLower(mediaType)]
+			if !ok {
+				continue
+			}
+			ref, err := url.Parse(attrs["href"])
+			if err != nil || attrs["href"] == "" {
+				continue
+			}
+			u := base.ResolveReference(ref).String()
+			if seen[u] {
+				continue
+			}
+			seen[u] = true
+			candidates = append(candidates, &Candidate{URL: u, Title: attrs["title"], Format: format})
+		}
+	}
+}
+
+// hasToken reports whether the space-separated
+// list s contains token, ignoring case.
+func hasToken(s, token string) bool {
+	for _, field := range strings.Fields(s) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// rankCandidates sorts candidates so that those
+// with a known format come first, and feeds of
+// comments come last. Otherwise, the page's own
+// order is kept, as sites tend to list their main
+// feed first.
+func rankCandidates(candidates []*Candidate) {
+	rank := func(c *Candidate) int {
+		r := 0
+		if c.Format == "" {
+			r++
+		}
+		if strings.Contains(strings.ToLower(c.Title), "comment") {
+			r += 2
+		}
+		return r
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return rank(candidates[i]) < rank(candidates[j])
+	})
+}
@@ -0,0 +1,111 @@
+package rss
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const discoverPage = `<!DOCTYPE html>
+<html lang=en>
+<head>
+	<meta charset=utf-8>
+	<title>Example &mdash; Blog</title>
+	<base href="/blog/">
+	<link rel="stylesheet" href="style.css">
+	<link rel="alternate" type="application/rss+xml" title="Comments" href="comments/feed">
+	<link rel="alternate" type="text/xml" title="Old feed" href="old.xml">
+	<link rel="ALTERNATE" type="application/atom+xml; charset=utf-8" title="Example Blog" href="atom.xml">
+	<link rel="alternate" type="application/feed+json" title="Example JSON" href="https://cdn.example.com/feed.json">
+	<link rel="alternate" type="application/atom+xml" href="atom.xml">
+	<link rel="alternate" hreflang="fr" href="/fr/">
+	<script>if (a < b && c) { run(); }</script>
+</head>
+<body>
+	<link rel="alternate" type="application/rss+xml" href="/ignored">
+</body>
+</html>`
+
+func TestDiscover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(discoverPage))
+		case "/plain":
+			w.Write([]byte("<html><head><title>No feeds</title></head><body></body></html>"))
+		case "/atom", "/feed.xml":
+			data, _ := ioutil.ReadFile("testdata/atom_1.0")
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	got, err := DiscoverByRequestFunc(ctx, server.Client().Do, server.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Candidate{
+		{URL: server.URL + "/blog/atom.xml", Title: "Example Blog", Format: FormatAtom},
+		{URL: "https://cdn.example.com/feed.json", Title: "Example JSON", Format: FormatJSON},
+		{URL: server.URL + "/blog/old.xml", Title: "Old feed"},
+		{URL: server.URL + "/blog/comments/feed", Title: "Comments", Format: FormatRSS2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, c := range got {
+			t.Logf("got %+v", c)
+		}
+		t.Errorf("got %d candidates, want %d", len(got), len(want))
+	}
+
+	// A page with no links falls back to the
+	// usual places.
+	got, err = DiscoverByFunc(ctx, server.Client().Get, server.URL+"/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].URL != server.URL+"/feed.xml" || got[0].Format != FormatAtom {
+		t.Errorf("got fallback candidates %v", got)
+	}
+
+	// A feed is its own candidate.
+	got, err = DiscoverByRequestFunc(ctx, server.Client().Do, server.URL+"/atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].URL != server.URL+"/atom" || got[0].Title == "" {
+		t.Errorf("got candidates %v for a feed", got)
+	}
+
+	if _, err := DiscoverByRequestFunc(ctx, server.Client().Do, server.URL+"/missing"); err == nil {
+		t.Errorf("expected an error for a missing page")
+	}
+}
+
+func TestFindFeedLinksHTML(t *testing.T) {
+	base, _ := url.Parse("http://example.com/")
+	pages := map[string]string{
+		"script": `<html><head>
+			<script>if (a < b && c) { document.write("<link>"); }</script>
+			<style>a > b { color: red }</style>
+			<link rel="alternate" type="application/rss+xml" href="/f.xml">
+		</head><body></body></html>`,
+		"unquoted attributes": `<html><head>
+			<link rel=alternate type=application/rss+xml href=/f.xml>
+		</head><body></body></html>`,
+	}
+
+	for name, page := range pages {
+		got := findFeedLinks([]byte(page), base, "text/html")
+		if len(got) != 1 || got[0].URL != "http://example.com/f.xml" || got[0].Format != FormatRSS2 {
+			t.Errorf("%s: got candidates %v, want http://example.com/f.xml", name, got)
+		}
+	}
+}
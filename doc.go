@@ -1,9 +1,9 @@
 /*
-Package rss is a small library for simplifying the parsing of RSS and Atom feeds.
+Package rss is a small library for simplifying the parsing of RSS, Atom and JSON feeds.
 
-The package could do with more testing, but it conforms to the RSS 1.0, 2.0, and Atom 1.0
-specifications, to the best of my ability. I've tested it with about 15 different feeds,
-and it seems to work fine with them.
+The package could do with more testing, but it conforms to the RSS 1.0, 2.0, Atom 1.0 and
+JSON Feed 1.1 specifications, to the best of my ability. I've tested it with about 15 different
+feeds, and it seems to work fine with them.
 
 If anyone has any problems with feeds being parsed incorrectly, please let me know so that
 I can debug and improve the package.
@@ -28,6 +28,25 @@ Example usage:
 		}
 	}
 
+Very large feeds can be read with a Decoder, which returns one item at a time
+rather than holding the whole document in memory:
+
+	d := rss.NewDecoder(r)
+	for {
+		item, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// handle error.
+		}
+
+		// ... use item ...
+	}
+
+Feeds can also be written out again with Feed.WriteRSS2, Feed.WriteAtom and
+Feed.WriteJSONFeed, which is useful when republishing aggregated feeds.
+
 The output structure is pretty much as you'd expect:
 
 	type Feed struct {
@@ -63,10 +82,71 @@ The output structure is pretty much as you'd expect:
 
 The library does its best to follow the appropriate specifications and not to set the Refresh time
 too soon. It currently follows all update time management methods in the RSS 1.0, 2.0, and Atom 1.0
-specifications. If one is not provided, it defaults to 12 hour intervals (see DefaultRefreshInterval). If you are having issues
+specifications, including the Syndication module (sy:updatePeriod, sy:updateFrequency, and sy:updateBase), as well as the Cache-Control, Expires, and Retry-After HTTP headers (bounded by MinRefreshInterval
+and MaxRefreshInterval, and ignored when they only say not to cache the feed). If none is provided, it defaults to 12 hour intervals (see DefaultRefreshInterval). If you are having issues
 with feed providors dropping connections, please let me know and I can increase this default, or you
 can increase the Refresh time manually. The Feed.Update method uses this Refresh time, so if Update
 seems to be returning very quickly with no new items, it's likely not making a request due to the
 provider's Refresh interval.
+
+To keep many feeds up to date, add them to an Aggregator (see NewAggregator). It updates each feed when it is due,
+with a limit on how many are fetched at once, both overall and from any one host, and delivers new items on a
+channel or to a callback.
+
+A Feed's methods are safe for concurrent use, so one goroutine can update a feed while another marks its items as
+read with MarkRead or MarkAllRead, or lists them with UnreadItems. These keep the Unread count in step with the items. Items
+are never changed once a feed holds them: an update that edits an item, MarkRead, and SetStarred all replace the item
+with a changed copy. Items that have been returned can therefore be read without a lock, but show the item as it was.
+A Feed can also be saved with json.Marshal while it is being updated. Its other fields should only be read through
+its methods while another goroutine may be using it.
+
+Long-lived feeds can be kept to a manageable size by setting Feed.Retention, or DefaultRetention for all feeds. A
+Retention keeps at most MaxItems items and drops those older than MaxAge after each update, but never prunes items
+that are unread or starred (see SetStarred). The IDs of the most recently pruned items are kept in Feed.Seen, so
+that they are not added again as new while they remain in the feed's document.
+
+To keep feeds across restarts, give them a Store, either by setting Feed.Store or AggregatorConfig.Store. Each
+update then saves the feed's metadata and its new, edited, and pruned items, and MarkRead, MarkAllRead, and
+SetStarred save the items' state. The built-in FileStore (see NewFileStore) keeps each feed in a file that is
+replaced atomically, with its items in a log that is only appended to and is compacted as it grows. LoadFeeds, or
+Aggregator.Load, reads the feeds back.
+
+To find the feeds for a website, pass the address of one of its pages to Discover. It returns candidates
+from the page's <link rel="alternate"> tags, with their titles and formats, best first, or checks common
+locations such as /feed and /rss.xml if the page links to none.
+
+Subscription lists can be moved between readers as OPML. ParseOPML reads an outline, keeping its folders,
+categories, and any attributes it does not know, and OPML.Feeds lists the subscriptions in it as Feeds ready to
+update. WriteOPML writes a list of Feeds, and OPML.Write writes a parsed outline back out.
+
+Podcast feeds in RSS 2.0 or Atom have their iTunes metadata (the itunes: elements used by Apple Podcasts) in
+Feed.Podcast and Item.Podcast, including each episode's duration, season and episode numbers, and explicit flag, and
+the show's owner, type, artwork, and categories. Where a feed leaves out a standard element, such as an item's
+title or the feed's author, the iTunes one is used instead.
+
+Podcasting 2.0 metadata (the podcast: elements from podcastindex.org) is in Feed.PodcastIndex and
+Item.PodcastIndex: transcripts, chapters, funding links, people, the locked flag and podcast GUID, value-for-value
+payment details, soundbites, and alternate enclosures with all their sources. Transcripts and chapters can be fetched
+like enclosures, with Get, and PodcastChapters.Chapters fetches and parses a JSON chapters file.
+
+Media RSS (the media: elements used by YouTube, Flickr and many news sites) is in Item.Media, with each media:group
+holding the renditions of the same media, such as at several sizes, each with its URL, medium, size, bitrate and
+duration, along with thumbnails, players and credits. An item with no image of its own uses the largest thumbnail,
+or failing that the largest image, and one with no summary uses the media description.
+
+Dublin Core metadata (the dc: and dcterms: elements, which RSS 1.0 feeds rely on) is in Feed.DublinCore and
+Item.DublinCore. Creators, the language, titles and descriptions fill in the author, language, title and summary of a
+feed or item that has none of its own, dc:date fills in a missing item date, and subjects are added to the categories.
+
+How the Refresh time is chosen can be changed by setting Feed.RefreshPolicy, or DefaultRefreshPolicy for all
+feeds. An *AdaptiveRefreshPolicy checks each feed about as often as it publishes new items, judging by the dates of
+the items seen so far, and backs off after failed updates.
+
+Errors can be inspected with errors.Is and errors.As. Calling Update before the Refresh time returns a
+*TooSoonError (which matches ErrTooSoon) holding the next refresh time, and malformed documents produce a
+*ParseError with the format, line, and a snippet of the text near the problem. Documents in no supported format
+match ErrUnknownFormat. Responses with a status other than 2xx or 304 produce an *HTTPStatusError; a 410 Gone
+matches ErrGone, meaning the feed should be dropped, and a Retry-After header on the response pushes back the
+feed's Refresh time.
 */
 package rss
@@ -0,0 +1,149 @@
+package rss
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// nsDublinCore and nsDCTerms are the namespaces of
+// the Dublin Core elements, which RSS 1.0 feeds use
+// for most of their metadata, and which are common
+// in RSS 2.0 feeds too.
+const (
+	nsDublinCore = "http://purl.org/dc/elements/1.1/"
+	nsDCTerms    = "http://purl.org/dc/terms/"
+)
+
+// DublinCore holds the Dublin Core metadata of a
+// feed or an item.
+type DublinCore struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Creators    []string  `json:"creators,omitempty"`
+	Subjects    []string  `json:"subjects,omitempty"`
+	Rights      string    `json:"rights,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	Publisher   string    `json:"publisher,omitempty"`
+	Date        time.Time `json:"date,omitempty"` // Zero if not given or invalid.
+}
+
+// dublinCoreElements holds the elements in the
+// Dublin Core namespaces of a channel or an item.
+type dublinCoreElements struct {
+	Title       string   `xml:"title"`
+	Description string   `xml:"description"`
+	Creators    []string `xml:"creator"`
+	Subjects    []string `xml:"subject"`
+	Rights      string   `xml:"rights"`
+	Language    string   `xml:"language"`
+	Publisher   string   `xml:"publisher"`
+	Date        string   `xml:"date"`
+}
+
+// register adds dc to ext for the Dublin Core
+// namespaces, including feeds that use the dc
+// prefix without declaring it.
+func (dc *dublinCoreElements) register(ext map[string]interface{}) {
+	ext[nsDublinCore] = dc
+	ext[nsDCTerms] = dc
+	ext["dc"] = dc
+	ext["dcterms"] = dc
+}
+
+// DublinCore returns the Dublin Core metadata, or
+// nil if there was none.
+func (dc *dublinCoreElements) DublinCore() *DublinCore {
+	out := &DublinCore{
+		Title:       strings.TrimSpace(dc.Title),
+		Description: strings.TrimSpace(dc.Description),
+		Creators:    trimAll(dc.Creators),
+		Subjects:    trimAll(dc.Subjects),
+		Rights:      strings.TrimSpace(dc.Rights),
+		Language:    strings.TrimSpace(dc.Language),
+		Publisher:   strings.TrimSpace(dc.Publisher),
+	}
+	if t, ok := parseW3CDTF(dc.Date); ok {
+		out.Date = t
+	}
+
+	if reflect.DeepEqual(out, &DublinCore{}) {
+		return nil
+	}
+	return out
+}
+
+// fillFeed sets f's title, description, author
+// and language from dc where the feed has none of
+// its own. Subjects are added to the categories.
+func (dc *DublinCore) fillFeed(f *Feed) {
+	if f.Title == "" {
+		f.Title = dc.Title
+	}
+	if f.Description == "" {
+		f.Description = dc.Description
+	}
+	if f.Author == "" {
+		f.Author = dc.author()
+	}
+	if f.Language == "" {
+		f.Language = dc.Language
+	}
+	f.Categories = addCategories(f.Categories, dc.Subjects)
+}
+
+// fillItem sets item's title, summary, author
+// and date from dc where the item has none of its
+// own. Subjects are added to the categories.
+func (dc *DublinCore) fillItem(item *Item) {
+	if item.Title == "" {
+		item.Title = dc.Title
+	}
+	if item.Summary == "" {
+		item.Summary = dc.Description
+	}
+	if item.Author == "" {
+		item.Author = dc.author()
+	}
+	if !item.DateValid && !dc.Date.IsZero() {
+		item.Date = dc.Date
+		item.DateValid = true
+	}
+	item.Categories = addCategories(item.Categories, dc.Subjects)
+}
+
+// author returns the creators as a single author,
+// or the publisher if there are none.
+func (dc *DublinCore) author() string {
+	if len(dc.Creators) == 0 {
+		return dc.Publisher
+	}
+	return strings.Join(dc.Creators, ", ")
+}
+
+// addCategories returns categories with those in
+// more added, leaving out any already there.
+func addCategories(categories, more []string) []string {
+next:
+	for _, category := range more {
+		for _, have := range categories {
+			if strings.EqualFold(have, category) {
+				continue next
+			}
+		}
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// trimAll returns the non-empty strings in s,
+// with surrounding space removed.
+func trimAll(s []string) []string {
+	var out []string
+	for _, v := range s {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
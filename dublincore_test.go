@@ -0,0 +1,127 @@
+package rss
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDublinCoreRSS1(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/rss_1.0_syndication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &DublinCore{
+		Creators:  []string{"Rael Dornfest (mailto:rael@oreilly.com)"},
+		Rights:    "Copyright © 2000 O'Reilly & Associates, Inc.",
+		Publisher: "The O'Reilly Network",
+		Date:      time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if dc := feed.DublinCore; dc == nil || !dc.Date.Equal(want.Date) {
+		t.Fatalf("got Dublin Core %+v, want %+v", dc, want)
+	}
+	want.Date = feed.DublinCore.Date
+	if !reflect.DeepEqual(feed.DublinCore, want) {
+		t.Errorf("got Dublin Core %+v, want %+v", feed.DublinCore, want)
+	}
+	assertEqual("Rael Dornfest (mailto:rael@oreilly.com)", feed.Author, t)
+
+	item := feed.Items[0]
+	assertEqual("Simon St.Laurent (mailto:simonstl@simonstl.com)", item.Author, t)
+	if !strings.HasPrefix(item.Summary, "XML is placing increasingly heavy loads") {
+		t.Errorf("got summary %q, want the dc:description", item.Summary)
+	}
+	if !reflect.DeepEqual(item.Categories, []string{"XML"}) {
+		t.Errorf("got categories %q, want [XML]", item.Categories)
+	}
+	if dc := item.DublinCore; dc == nil || dc.Publisher != "The O'Reilly Network" || !strings.HasPrefix(dc.Rights, "Copyright") {
+		t.Errorf("got Dublin Core %+v", dc)
+	}
+}
+
+func TestParseDublinCoreRSS2(t *testing.T) {
+	feed := mustParse(t, `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>
+		<title>Blog</title>
+		<dc:language>en-GB</dc:language>
+		<dc:subject>Cooking</dc:subject>
+		<item><guid>1</guid><title>Bread</title>
+			<category>Baking</category>
+			<dc:creator><![CDATA[Alice]]></dc:creator>
+			<dc:creator>Bob</dc:creator>
+			<dc:subject>baking</dc:subject>
+			<dc:subject>Yeast</dc:subject>
+			<dc:date>2023-03-01T09:00:00Z</dc:date>
+		</item>
+		<item><guid>2</guid><author>carol@example.com (Carol)</author>
+			<pubDate>Thu, 02 Mar 2023 10:00:00 GMT</pubDate>
+			<dc:creator>Someone Else</dc:creator>
+			<dc:date>2000-01-01T00:00:00Z</dc:date>
+		</item>
+	</channel></rss>`)
+
+	assertEqual("en-GB", feed.Language, t)
+	if !reflect.DeepEqual(feed.Categories, []string{"Cooking"}) {
+		t.Errorf("got feed categories %q, want [Cooking]", feed.Categories)
+	}
+
+	bread := feed.Items[0]
+	assertEqual("Alice, Bob", bread.Author, t)
+	if !reflect.DeepEqual(bread.Categories, []string{"Baking", "Yeast"}) {
+		t.Errorf("got categories %q, want [Baking Yeast]", bread.Categories)
+	}
+	if !bread.DateValid || !bread.Date.Equal(time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("got date %v (valid %v), want the dc:date", bread.Date, bread.DateValid)
+	}
+
+	// The item's own author and date are kept.
+	second := feed.Items[1]
+	assertEqual("carol@example.com (Carol)", second.Author, t)
+	if !second.Date.Equal(time.Date(2023, 3, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("got date %v, want the pubDate", second.Date)
+	}
+}
+
+func TestParseDublinCoreNamespaces(t *testing.T) {
+	// Only elements in the Dublin Core namespaces
+	// are taken as Dublin Core, and they do not
+	// replace the standard elements with the same
+	// local names.
+	feed := mustParse(t, `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:x="http://example.com/x">
+		<channel><title>Feed</title><language>fr</language><dc:language>de</dc:language>
+		<item><guid>1</guid><title>Real</title><dc:title>Other</dc:title>
+			<x:creator>Not Dublin Core</x:creator><x:date>2023-01-01T00:00:00Z</x:date>
+		</item>
+		<item><guid>2</guid><dcterms:creator>Dee</dcterms:creator></item>
+	</channel></rss>`)
+
+	assertEqual("fr", feed.Language, t)
+	if dc := feed.DublinCore; dc == nil || dc.Language != "de" {
+		t.Errorf("got Dublin Core %+v", dc)
+	}
+	item := feed.Items[0]
+	assertEqual("Real", item.Title, t)
+	assertEqual("", item.Author, t)
+	if item.DateValid {
+		t.Errorf("got date %v from a non-Dublin Core element", item.Date)
+	}
+	if dc := item.DublinCore; dc == nil || dc.Title != "Other" || len(dc.Creators) != 0 {
+		t.Errorf("got Dublin Core %+v", dc)
+	}
+	assertEqual("Dee", feed.Items[1].Author, t)
+
+	atom := mustParse(t, `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">
+		<title>Atom</title><dc:creator>Eve</dc:creator>
+		<entry><id>1</id><dc:subject>News</dc:subject></entry>
+	</feed>`)
+	assertEqual("Eve", atom.Author, t)
+	if !reflect.DeepEqual(atom.Items[0].Categories, []string{"News"}) {
+		t.Errorf("got categories %q, want [News]", atom.Items[0].Categories)
+	}
+}
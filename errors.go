@@ -0,0 +1,250 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrNotModified is returned when the server reports
+	// that a feed has not changed since it was last fetched.
+	// Feed.Update treats this as success.
+	ErrNotModified = errors.New("feed not modified")
+
+	// ErrTooSoon is returned by Feed.Update when the feed
+	// is not yet due to be refreshed. The error returned
+	// is a *TooSoonError, which holds the refresh time.
+	ErrTooSoon = errors.New("not ready to update: too soon to refresh")
+
+	// ErrUnknownFormat is returned when a document is not
+	// in any of the supported feed formats.
+	ErrUnknownFormat = errors.New("unknown feed format")
+
+	// ErrNoURL is returned when updating a feed with no
+	// UpdateURL.
+	ErrNoURL = errors.New("feed has no URL")
+
+	// ErrNoEnclosure is returned when fetching a missing
+	// enclosure.
+	ErrNoEnclosure = errors.New("no enclosure")
+
+	// ErrNoImage is returned when fetching a missing image.
+	ErrNoImage = errors.New("no image")
+
+	// ErrNoTranscript is returned when fetching a
+	// missing transcript.
+	ErrNoTranscript = errors.New("no transcript")
+
+	// ErrNoChapters is returned when fetching missing
+	// chapters.
+	ErrNoChapters = errors.New("no chapters")
+
+	// ErrNotStored is returned by a Store when asked
+	// for a feed that it does not hold.
+	ErrNotStored = errors.New("feed not stored")
+
+	// ErrNoStore is returned when saving a feed with
+	// no Store.
+	ErrNoStore = errors.New("feed has no store")
+
+	// ErrGone matches an *HTTPStatusError for a feed that
+	// the server reports has been permanently removed.
+	// Callers should stop polling it.
+	ErrGone = errors.New("feed is gone")
+
+	// ErrStopped is returned when adding a feed to an
+	// Aggregator that has been stopped.
+	ErrStopped = errors.New("aggregator stopped")
+)
+
+// TooSoonError is returned by Feed.Update when the
+// feed is not yet due to be refreshed. It matches
+// ErrTooSoon with errors.Is.
+type TooSoonError struct {
+	Refresh time.Time // Earliest time the feed should next be checked.
+}
+
+var _ net.Error = (*TooSoonError)(nil)
+
+func (e *TooSoonError) Error() string {
+	return fmt.Sprintf("%v (next refresh at %s)", ErrTooSoon, e.Refresh.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrTooSoon.
+func (e *TooSoonError) Is(target error) bool {
+	return target == ErrTooSoon
+}
+
+// Timeout returns false.
+func (e *TooSoonError) Timeout() bool {
+	return false
+}
+
+// Temporary returns true, as the feed can be
+// updated once its refresh time has passed.
+func (e *TooSoonError) Temporary() bool {
+	return true
+}
+
+// HTTPStatusError is returned when a server
+// responds with an unexpected status code.
+//
+// If the response included a Retry-After header,
+// RetryAfter holds the time it gave, and Feed.Update
+// will not fetch the feed again before then.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	RetryAfter time.Time
+}
+
+func (e *HTTPStatusError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("fetching %s: unexpected HTTP status %s", e.URL, status)
+}
+
+// Temporary reports whether the request may
+// succeed if it is retried later.
+func (e *HTTPStatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return e.StatusCode >= 500
+}
+
+// Permanent reports whether the feed has been
+// removed for good (410 Gone), in which case it
+// should not be fetched again.
+func (e *HTTPStatusError) Permanent() bool {
+	return e.StatusCode == http.StatusGone
+}
+
+// Is reports whether target is ErrGone and the
+// error is permanent.
+func (e *HTTPStatusError) Is(target error) bool {
+	return target == ErrGone && e.Permanent()
+}
+
+// ParseError is returned when a feed cannot be
+// parsed.
+type ParseError struct {
+	Format  Format // Empty if the format was not determined.
+	Line    int    // Line of the error, starting at 1, or 0 if unknown.
+	Offset  int64  // Byte offset of the error in the document.
+	Snippet string // Part of the document before the error, if known.
+	Err     error  // Underlying error.
+}
+
+func (e *ParseError) Error() string {
+	buf := new(bytes.Buffer)
+	buf.WriteString("parsing ")
+	if e.Format != "" {
+		buf.WriteString(string(e.Format))
+	} else {
+		buf.WriteString("feed")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(buf, " (line %d)", e.Line)
+	} else {
+		fmt.Fprintf(buf, " (offset %d)", e.Offset)
+	}
+	fmt.Fprintf(buf, ": %v", e.Err)
+	if e.Snippet != "" {
+		fmt.Fprintf(buf, " near %q", e.Snippet)
+	}
+	return buf.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// snippetLength is the maximum length of
+// ParseError.Snippet.
+const snippetLength = 64
+
+// recorder keeps the most recent bytes read from
+// a reader, so that parse errors can show the
+// surrounding text without the whole document
+// being held in memory.
+type recorder struct {
+	r     io.Reader
+	buf   []byte
+	start int64 // Offset of buf[0] in the input.
+	lines int   // Number of newlines before buf[0].
+	err   error // Error returned by r, other than io.EOF.
+}
+
+// recorderSize is the number of bytes a recorder
+// keeps. This must exceed the read-ahead of the
+// decoders for snippets to be available.
+const recorderSize = 16 << 10
+
+func (r *recorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	if extra := len(r.buf) - recorderSize; extra > 0 {
+		r.lines += bytes.Count(r.buf[:extra], []byte{'\n'})
+		r.start += int64(extra)
+		r.buf = append(r.buf[:0], r.buf[extra:]...)
+	}
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// position returns the line of the given offset
+// and the text preceding it, if they are known.
+func (r *recorder) position(offset int64) (line int, snippet string) {
+	if offset < r.start || offset > r.start+int64(len(r.buf)) {
+		return 0, ""
+	}
+
+	before := r.buf[:offset-r.start]
+	line = r.lines + bytes.Count(before, []byte{'\n'}) + 1
+	if len(before) > snippetLength {
+		before = before[len(before)-snippetLength:]
+	}
+	return line, string(bytes.ToValidUTF8(before, nil))
+}
+
+// parseError wraps err, returned at offset in the
+// input, in a ParseError. Errors from the reader
+// itself are returned unchanged.
+func (r *recorder) parseError(format Format, offset int64, err error) error {
+	if err == r.err {
+		return err
+	}
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		return err
+	}
+
+	line, snippet := r.position(offset)
+
+	var syntax *xml.SyntaxError
+	if errors.As(err, &syntax) {
+		line = syntax.Line
+	}
+
+	return &ParseError{
+		Format:  format,
+		Line:    line,
+		Offset:  offset,
+		Snippet: snippet,
+		Err:     err,
+	}
+}
@@ -0,0 +1,142 @@
+package rss
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTooSoonError(t *testing.T) {
+	refresh := time.Now().Add(time.Hour)
+	feed := &Feed{UpdateURL: "http://example.com/feed", Refresh: refresh}
+
+	err := feed.UpdateByFunc(func(url string) (*http.Response, error) {
+		t.Fatal("Fetched a feed that was not ready to update")
+		return nil, nil
+	})
+	if !errors.Is(err, ErrTooSoon) {
+		t.Fatalf("got %v, want ErrTooSoon", err)
+	}
+
+	var tooSoon *TooSoonError
+	if !errors.As(err, &tooSoon) {
+		t.Fatalf("got %T, want *TooSoonError", err)
+	}
+	if !tooSoon.Refresh.Equal(refresh) {
+		t.Errorf("got refresh %v, want %v", tooSoon.Refresh, refresh)
+	}
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Temporary() {
+		t.Errorf("TooSoonError should be a temporary net.Error")
+	}
+}
+
+func TestNoURLError(t *testing.T) {
+	err := new(Feed).Update()
+	if !errors.Is(err, ErrNoURL) {
+		t.Errorf("got %v, want ErrNoURL", err)
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	data := "<rss version=\"2.0\">\n<channel>\n<title>Broken</title>\n<item><guid>1</guid></itme>\n</channel>\n</rss>"
+	_, err := Parse([]byte(data))
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %T (%v), want *ParseError", err, err)
+	}
+	if perr.Format != FormatRSS2 {
+		t.Errorf("got format %q, want %q", perr.Format, FormatRSS2)
+	}
+	if perr.Line != 4 {
+		t.Errorf("got line %d, want 4", perr.Line)
+	}
+	if !strings.Contains(perr.Snippet, "</itme>") {
+		t.Errorf("snippet %q does not contain the error", perr.Snippet)
+	}
+	if len(perr.Snippet) > snippetLength {
+		t.Errorf("snippet is %d bytes, want at most %d", len(perr.Snippet), snippetLength)
+	}
+}
+
+func TestParseErrorJSON(t *testing.T) {
+	data := "\n\n{\"version\": \"https://jsonfeed.org/version/1.1\",\n\"items\": [{\"id\": 1,}]}"
+	_, err := Parse([]byte(data))
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %T (%v), want *ParseError", err, err)
+	}
+	if perr.Format != FormatJSON {
+		t.Errorf("got format %q, want %q", perr.Format, FormatJSON)
+	}
+	if perr.Line != 4 {
+		t.Errorf("got line %d, want 4", perr.Line)
+	}
+}
+
+func TestParseErrorUnknownFormat(t *testing.T) {
+	for _, data := range []string{"", "  \n", "<html><body>Not a feed</body></html>"} {
+		_, err := Parse([]byte(data))
+		if !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("%q: got %v, want ErrUnknownFormat", data, err)
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: got %T, want *ParseError", data, err)
+		}
+	}
+}
+
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestParseReaderError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	_, err := ParseReader(&failingReader{data: `<rss version="2.0"><channel><item>`, err: readErr})
+	if err != readErr {
+		t.Errorf("got %v, want the reader's error", err)
+	}
+	_, err = ParseReader(&failingReader{err: io.ErrUnexpectedEOF})
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want the reader's error", err)
+	}
+}
+
+func TestHTTPStatusError(t *testing.T) {
+	tests := map[int]bool{
+		404: false,
+		410: false,
+		408: true,
+		429: true,
+		500: true,
+		503: true,
+	}
+
+	for code, temporary := range tests {
+		err := &HTTPStatusError{URL: "http://example.com/feed", StatusCode: code}
+		if err.Temporary() != temporary {
+			t.Errorf("%d: got Temporary() %v, want %v", code, err.Temporary(), temporary)
+		}
+		if !strings.Contains(err.Error(), "http://example.com/feed") {
+			t.Errorf("%d: error %q does not include the URL", code, err)
+		}
+	}
+}
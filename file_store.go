@@ -0,0 +1,428 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore is a Store that keeps each feed in a
+// directory as two files: one holding the feed
+// itself, which is replaced atomically whenever it
+// is saved, and a log of changes to its items, which
+// is only appended to. The log is compacted once it
+// has grown to hold many more entries than items.
+//
+// A FileStore is safe for concurrent use, but only
+// one FileStore should use a directory at once.
+type FileStore struct {
+	dir string
+
+	mu   sync.Mutex
+	logs map[string]logSize // By name, for the logs read so far.
+}
+
+// logSize records the size of a feed's log when
+// it was last read, and the entries added since.
+type logSize struct {
+	entries int
+	items   int
+}
+
+// NewFileStore returns a FileStore that keeps
+// feeds in dir, which is created if necessary.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir, logs: make(map[string]logSize)}, nil
+}
+
+// storedFeed is the part of a Feed that is saved
+// in its feed file. The items are kept in the log.
+// It embeds a plainFeed, as SaveFeed is called
+// with the feed locked.
+type storedFeed struct {
+	*plainFeed
+	Items   []*Item             `json:"items,omitempty"`
+	ItemMap map[string]struct{} `json:"itemmap,omitempty"`
+}
+
+// logEntry is one change in a feed's log.
+type logEntry struct {
+	Item   *Item    `json:"item,omitempty"`
+	Delete []string `json:"delete,omitempty"`
+	Read   []string `json:"read,omitempty"`
+	Unread []string `json:"unread,omitempty"`
+}
+
+// name returns the name of the files for the feed
+// with the given URL, without an extension.
+func (s *FileStore) name(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (s *FileStore) feedPath(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+func (s *FileStore) logPath(name string) string {
+	return filepath.Join(s.dir, name+".log")
+}
+
+// FeedURLs implements Store.
+func (s *FileStore) FeedURLs() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for _, path := range paths {
+		f, err := s.readFeed(path)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, f.UpdateURL)
+	}
+
+	return urls, nil
+}
+
+// LoadFeed implements Store.
+func (s *FileStore) LoadFeed(url string) (*Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := s.name(url)
+	f, err := s.readFeed(s.feedPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.readItems(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Items = items
+	f.buildItemMap()
+	f.countUnread()
+
+	return f, nil
+}
+
+// SaveFeed implements Store.
+func (s *FileStore) SaveFeed(f *Feed) error {
+	data, err := json.Marshal(storedFeed{plainFeed: (*plainFeed)(f)})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeFileAtomic(s.feedPath(s.name(f.UpdateURL)), data)
+}
+
+// SaveItems implements Store.
+func (s *FileStore) SaveItems(url string, items []*Item) error {
+	entries := make([]logEntry, len(items))
+	for i, item := range items {
+		entries[i].Item = item
+	}
+
+	return s.appendLog(url, entries...)
+}
+
+// DeleteItems implements Store.
+func (s *FileStore) DeleteItems(url string, ids []string) error {
+	return s.appendLog(url, logEntry{Delete: ids})
+}
+
+// SetRead implements Store.
+func (s *FileStore) SetRead(url string, ids []string, read bool) error {
+	if read {
+		return s.appendLog(url, logEntry{Read: ids})
+	}
+	return s.appendLog(url, logEntry{Unread: ids})
+}
+
+// SeenIDs implements Store.
+func (s *FileStore) SeenIDs(url string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := s.name(url)
+	f, err := s.readFeed(s.feedPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.readItems(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(items)+len(f.Seen))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return append(ids, f.Seen...), nil
+}
+
+// DeleteFeed implements Store.
+func (s *FileStore) DeleteFeed(url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := s.name(url)
+	delete(s.logs, name)
+	for _, path := range []string{s.feedPath(name), s.logPath(name)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readFeed reads a feed file. It must be called
+// with s.mu held.
+func (s *FileStore) readFeed(path string) (*Feed, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := new(Feed)
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	f.ItemMap = nil
+
+	return f, nil
+}
+
+// readItems replays the log of the feed with the
+// given name, returning its items, and compacts it
+// if needed. It must be called with s.mu held.
+func (s *FileStore) readItems(name string) ([]*Item, error) {
+	file, err := os.Open(s.logPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		s.logs[name] = logSize{}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var items []*Item
+	index := make(map[string]int)
+	set := func(ids []string, read bool) {
+		for _, id := range ids {
+			if i, ok := index[id]; ok {
+				items[i].Read = read
+			}
+		}
+	}
+
+	entries := 0
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// Any partial line is from a write
+			// that was interrupted.
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var entry logEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// So is any line that is not valid,
+			// as appendLog starts a new line
+			// after one.
+			continue
+		}
+		entries++
+
+		if item := entry.Item; item != nil {
+			if i, ok := index[item.ID]; ok {
+				items[i] = item
+			} else {
+				index[item.ID] = len(items)
+				items = append(items, item)
+			}
+		}
+		if len(entry.Delete) > 0 {
+			drop := make(map[string]bool, len(entry.Delete))
+			for _, id := range entry.Delete {
+				drop[id] = true
+			}
+			kept := items[:0]
+			for _, item := range items {
+				if drop[item.ID] {
+					delete(index, item.ID)
+					continue
+				}
+				index[item.ID] = len(kept)
+				kept = append(kept, item)
+			}
+			items = kept
+		}
+		set(entry.Read, true)
+		set(entry.Unread, false)
+	}
+
+	s.logs[name] = logSize{entries: entries, items: len(items)}
+	if s.logs[name].tooLong() {
+		if err := s.compact(name, items); err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// tooLong reports whether the log should be
+// compacted.
+func (l logSize) tooLong() bool {
+	return l.entries > 2*l.items+64
+}
+
+// compact replaces the log of the feed with the
+// given name with one holding only its items. It
+// must be called with s.mu held.
+func (s *FileStore) compact(name string, items []*Item) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, item := range items {
+		if err := enc.Encode(logEntry{Item: item}); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileAtomic(s.logPath(name), buf.Bytes()); err != nil {
+		return err
+	}
+	s.logs[name] = logSize{entries: len(items), items: len(items)}
+
+	return nil
+}
+
+// appendLog adds entries to the log of the feed
+// with the given URL.
+func (s *FileStore) appendLog(url string, entries ...logEntry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := s.name(url)
+	if _, ok := s.logs[name]; !ok {
+		// Read the log to learn its size, so that
+		// it is compacted in time.
+		if _, err := s.readItems(name); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(s.logPath(name), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	// If the last write was interrupted, start
+	// on a new line.
+	data := buf.Bytes()
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	size := s.logs[name]
+	size.entries += len(entries)
+	s.logs[name] = size
+	if size.tooLong() {
+		// Reading the log compacts it if it
+		// is still too long.
+		_, err = s.readItems(name)
+	}
+
+	return err
+}
+
+// writeFileAtomic replaces the file at path with
+// data, so that a crash leaves either the old file
+// or the new one, never a mixture.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Make the rename itself durable, where the
+	// system allows it.
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	return nil
+}
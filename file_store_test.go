@@ -0,0 +1,337 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFileStore(t *testing.T) *FileStore {
+	t.Helper()
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func itemIDs(f *Feed) []string {
+	var ids []string
+	for _, item := range f.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestFileStore(t *testing.T) {
+	s := newTestFileStore(t)
+	url := "http://example.com/feed"
+
+	if _, err := s.LoadFeed(url); err != ErrNotStored {
+		t.Fatalf("got %v loading a missing feed, want ErrNotStored", err)
+	}
+
+	feed := &Feed{Title: "Example", UpdateURL: url, Seen: []string{"0"}}
+	for _, id := range []string{"1", "2", "3"} {
+		feed.Items = append(feed.Items, &Item{ID: id, Title: "Item " + id})
+	}
+	if err := s.SaveFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveItems(url, feed.Items); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveItems(url, []*Item{{ID: "2", Title: "Edited", Starred: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetRead(url, []string{"1", "2"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteItems(url, []string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.LoadFeed(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual("Example", got.Title, t)
+	if ids := itemIDs(got); !reflect.DeepEqual(ids, []string{"2", "3"}) {
+		t.Fatalf("got items %v, want [2 3]", ids)
+	}
+	item := got.Items[0]
+	if item.Title != "Edited" || !item.Read || !item.Starred {
+		t.Errorf("got item %+v, want it edited, read and starred", item)
+	}
+	if got.Unread != 1 {
+		t.Errorf("got %d unread, want 1", got.Unread)
+	}
+	if _, ok := got.ItemMap["3"]; !ok {
+		t.Errorf("item map was not rebuilt")
+	}
+
+	seen, err := s.SeenIDs(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(seen, []string{"2", "3", "0"}) {
+		t.Errorf("got seen IDs %v, want [2 3 0]", seen)
+	}
+
+	urls, err := s.FeedURLs()
+	if err != nil || !reflect.DeepEqual(urls, []string{url}) {
+		t.Errorf("got URLs %v, %v", urls, err)
+	}
+
+	if err := s.DeleteFeed(url); err != nil {
+		t.Fatal(err)
+	}
+	if urls, _ := s.FeedURLs(); len(urls) != 0 {
+		t.Errorf("got URLs %v after deleting the feed", urls)
+	}
+	if files, _ := os.ReadDir(s.dir); len(files) != 0 {
+		t.Errorf("left %d files behind", len(files))
+	}
+}
+
+func TestFileStoreInterruptedWrite(t *testing.T) {
+	s := newTestFileStore(t)
+	url := "http://example.com/feed"
+	if err := s.SaveFeed(&Feed{UpdateURL: url}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveItems(url, []*Item{{ID: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash part way through a write.
+	path := s.logPath(s.name(url))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"item":{"id":"2","tit`)
+	file.Close()
+
+	s = &FileStore{dir: s.dir, logs: make(map[string]logSize)}
+	if err := s.SaveItems(url, []*Item{{ID: "3"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.LoadFeed(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := itemIDs(got); !reflect.DeepEqual(ids, []string{"1", "3"}) {
+		t.Errorf("got items %v, want [1 3]", ids)
+	}
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	s := newTestFileStore(t)
+	url := "http://example.com/feed"
+	if err := s.SaveFeed(&Feed{UpdateURL: url}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveItems(url, []*Item{{ID: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		if err := s.SetRead(url, []string{"1"}, i%2 == 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(s.logPath(s.name(url)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines > 67 {
+		t.Errorf("log has %d lines, want it compacted", lines)
+	}
+
+	got, err := s.LoadFeed(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Items) != 1 || got.Items[0].Read {
+		t.Errorf("got items %v, want item 1 unread", got.Items)
+	}
+}
+
+func TestUpdateStore(t *testing.T) {
+	s := newTestFileStore(t)
+	doc := `<rss version="2.0"><channel><title>Example</title><ttl>-1</ttl>
+		<item><guid>1</guid><title>First</title></item>
+	</channel></rss>`
+	fetch := func(url string) (*http.Response, error) {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(doc))}, nil
+	}
+
+	feed, err := FetchByFunc(fetch, "http://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.Store = s
+	if err := feed.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc = strings.Replace(doc, "<item>", "<item><guid>2</guid><title>Second</title></item><item>", 1)
+	if err := feed.Update(); err != nil {
+		t.Fatal(err)
+	}
+	feed.MarkRead("1")
+	feed.SetStarred("2", true)
+
+	feeds, err := LoadFeeds(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 {
+		t.Fatalf("got %d feeds, want 1", len(feeds))
+	}
+	got := feeds[0]
+	if got.Store != Store(s) {
+		t.Errorf("loaded feed does not use the store")
+	}
+	assertEqual("Example", got.Title, t)
+	if ids := itemIDs(got); !reflect.DeepEqual(ids, []string{"1", "2"}) {
+		t.Fatalf("got items %v, want [1 2]", ids)
+	}
+	if !got.Items[0].Read || got.Items[1].Read || !got.Items[1].Starred || got.Unread != 1 {
+		t.Errorf("item state was not saved: %v %v", got.Items[0], got.Items[1])
+	}
+	if !got.Refresh.Equal(feed.Refresh) {
+		t.Errorf("got refresh %v, want %v", got.Refresh, feed.Refresh)
+	}
+}
+
+// failingStore is a Store whose writes fail
+// while fail is set.
+type failingStore struct {
+	*FileStore
+	fail bool
+}
+
+var errStore = errors.New("store failed")
+
+func (s *failingStore) SetRead(url string, ids []string, read bool) error {
+	if s.fail {
+		return errStore
+	}
+	return s.FileStore.SetRead(url, ids, read)
+}
+
+func (s *failingStore) SaveFeed(f *Feed) error {
+	if s.fail {
+		return errStore
+	}
+	return s.FileStore.SaveFeed(f)
+}
+
+// forgetfulStore is a Store that does not keep
+// Feed.Seen with the feed.
+type forgetfulStore struct {
+	*FileStore
+}
+
+func (s forgetfulStore) LoadFeed(url string) (*Feed, error) {
+	f, err := s.FileStore.LoadFeed(url)
+	if f != nil {
+		f.Seen = nil
+	}
+	return f, err
+}
+
+func TestLoadFeedsSeen(t *testing.T) {
+	s := forgetfulStore{newTestFileStore(t)}
+	url := "http://example.com/feed"
+
+	feed := &Feed{UpdateURL: url, Seen: []string{"1", "2"}, Store: s}
+	feed.Items = []*Item{{ID: "3"}}
+	if err := feed.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := LoadFeeds(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || !reflect.DeepEqual(feeds[0].Seen, []string{"1", "2"}) {
+		t.Fatalf("got feeds %+v, want one with Seen [1 2]", feeds)
+	}
+}
+
+func TestUpdateStoreFailure(t *testing.T) {
+	s := &failingStore{FileStore: newTestFileStore(t)}
+	doc := `<rss version="2.0"><channel><ttl>-1</ttl>
+		<item><guid>1</guid></item>
+	</channel></rss>`
+	feed := &Feed{
+		UpdateURL: "http://example.com/feed",
+		Store:     s,
+		FetchFunc: func(url string) (*http.Response, error) {
+			return &http.Response{Body: ioutil.NopCloser(strings.NewReader(doc))}, nil
+		},
+	}
+
+	s.fail = true
+	changes, err := feed.UpdateChanges(context.Background())
+	if !errors.Is(err, errStore) {
+		t.Fatalf("got error %v, want the store's error", err)
+	}
+	if changes == nil || !reflect.DeepEqual(changes.Added, []string{"1"}) {
+		t.Errorf("got changes %+v, want item 1 added", changes)
+	}
+	feed.MarkRead("1")
+
+	// The next update saves everything.
+	s.fail = false
+	feed.Refresh = time.Time{}
+	if err := feed.Update(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.LoadFeed(feed.UpdateURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Items) != 1 || !got.Items[0].Read {
+		t.Errorf("got items %v, want item 1 read", got.Items)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(s.dir, "*.tmp*")); len(matches) != 0 {
+		t.Errorf("left temporary files %v", matches)
+	}
+}
+
+func TestAggregatorStore(t *testing.T) {
+	server := newFeedServer(0)
+	defer server.Close()
+	s := newTestFileStore(t)
+
+	a := NewAggregator(AggregatorConfig{Store: s})
+	for _, path := range []string{"/rss_2.0", "/atom_1.0"} {
+		if err := a.Add(server.feed(path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.Remove(server.URL + "/atom_1.0")
+	a.Stop(context.Background())
+
+	a = NewAggregator(AggregatorConfig{Store: s})
+	defer a.Stop(context.Background())
+	if err := a.Load(); err != nil {
+		t.Fatal(err)
+	}
+	feeds := a.List()
+	if len(feeds) != 1 || feeds[0].UpdateURL != server.URL+"/rss_2.0" {
+		t.Fatalf("got %d feeds, want only rss_2.0", len(feeds))
+	}
+}
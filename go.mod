@@ -2,4 +2,7 @@ module github.com/SlyMarbo/rss
 
 go 1.17
 
-require github.com/axgle/mahonia v0.0.0-20180208002826-3358181d7394
+require (
+	github.com/axgle/mahonia v0.0.0-20180208002826-3358181d7394
+	golang.org/x/net v0.17.0
+)
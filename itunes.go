@@ -0,0 +1,191 @@
+package rss
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nsITunes is the namespace of the iTunes podcast
+// elements used by Apple Podcasts and most other
+// podcast apps.
+const nsITunes = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// Podcast holds the iTunes podcast metadata of a
+// feed or an episode. Some fields only apply to
+// one or the other.
+type Podcast struct {
+	Title    string `json:"title,omitempty"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Summary  string `json:"summary,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Image    string `json:"image,omitempty"` // URL of the artwork.
+	Explicit bool   `json:"explicit,omitempty"`
+	Block    bool   `json:"block,omitempty"` // Whether the podcast or episode should be hidden from directories.
+
+	// These apply to a feed.
+	Owner      *PodcastOwner      `json:"owner,omitempty"`
+	Type       string             `json:"type,omitempty"` // "episodic" or "serial".
+	Categories []*PodcastCategory `json:"categories,omitempty"`
+	Complete   bool               `json:"complete,omitempty"`   // Whether no more episodes will be published.
+	NewFeedURL string             `json:"newfeedurl,omitempty"` // Where the podcast has moved to.
+
+	// These apply to an episode.
+	Duration    time.Duration `json:"duration,omitempty"`
+	Season      int           `json:"season,omitempty"`
+	Episode     int           `json:"episode,omitempty"`
+	EpisodeType string        `json:"episodetype,omitempty"` // "full", "trailer" or "bonus".
+}
+
+// PodcastOwner is the contact for a podcast.
+type PodcastOwner struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// PodcastCategory is one of the Apple Podcasts
+// categories, such as "Technology", with any
+// subcategories, such as "Podcasting".
+type PodcastCategory struct {
+	Name          string   `json:"name"`
+	Subcategories []string `json:"subcategories,omitempty"`
+}
+
+// itunesElements holds the elements in the
+// iTunes namespace of a channel or an item.
+type itunesElements struct {
+	Title       string           `xml:"title"`
+	Subtitle    string           `xml:"subtitle"`
+	Summary     string           `xml:"summary"`
+	Author      string           `xml:"author"`
+	Image       itunesImage      `xml:"image"`
+	Explicit    string           `xml:"explicit"`
+	Block       string           `xml:"block"`
+	Owner       *itunesOwner     `xml:"owner"`
+	Type        string           `xml:"type"`
+	Categories  []itunesCategory `xml:"category"`
+	Complete    string           `xml:"complete"`
+	NewFeedURL  string           `xml:"new-feed-url"`
+	Duration    string           `xml:"duration"`
+	Season      string           `xml:"season"`
+	Episode     string           `xml:"episode"`
+	EpisodeType string           `xml:"episodeType"`
+}
+
+type itunesOwner struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type itunesCategory struct {
+	Text          string           `xml:"text,attr"`
+	Subcategories []itunesCategory `xml:"category"`
+}
+
+// register adds i to ext for the iTunes namespace,
+// including feeds that use the itunes prefix
+// without declaring it.
+func (i *itunesElements) register(ext map[string]interface{}) {
+	ext[nsITunes] = i
+	ext["itunes"] = i
+}
+
+// Podcast returns the podcast metadata, or nil if
+// there was none.
+func (i *itunesElements) Podcast() *Podcast {
+	out := &Podcast{
+		Title:       strings.TrimSpace(i.Title),
+		Subtitle:    strings.TrimSpace(i.Subtitle),
+		Summary:     strings.TrimSpace(i.Summary),
+		Author:      strings.TrimSpace(i.Author),
+		Image:       strings.TrimSpace(i.Image.Href),
+		Explicit:    parseITunesBool(i.Explicit, "explicit"),
+		Block:       parseITunesBool(i.Block, ""),
+		Type:        strings.ToLower(strings.TrimSpace(i.Type)),
+		Complete:    parseITunesBool(i.Complete, ""),
+		NewFeedURL:  strings.TrimSpace(i.NewFeedURL),
+		Duration:    parseITunesDuration(i.Duration),
+		EpisodeType: strings.ToLower(strings.TrimSpace(i.EpisodeType)),
+	}
+	out.Season, _ = strconv.Atoi(strings.TrimSpace(i.Season))
+	out.Episode, _ = strconv.Atoi(strings.TrimSpace(i.Episode))
+	if i.Owner != nil {
+		out.Owner = &PodcastOwner{
+			Name:  strings.TrimSpace(i.Owner.Name),
+			Email: strings.TrimSpace(i.Owner.Email),
+		}
+	}
+	for _, category := range i.Categories {
+		next := &PodcastCategory{Name: strings.TrimSpace(category.Text)}
+		for _, sub := range category.Subcategories {
+			if name := strings.TrimSpace(sub.Text); name != "" {
+				next.Subcategories = append(next.Subcategories, name)
+			}
+		}
+		if next.Name != "" {
+			out.Categories = append(out.Categories, next)
+		}
+	}
+
+	if reflect.DeepEqual(out, &Podcast{}) {
+		return nil
+	}
+	return out
+}
+
+// categoryNames returns the names of the
+// top-level categories in p.
+func (p *Podcast) categoryNames() []string {
+	var names []string
+	for _, category := range p.Categories {
+		names = append(names, category.Name)
+	}
+	return names
+}
+
+// parseITunesBool parses a flag, which Apple now
+// gives as true or false, but older feeds give
+// as yes or no. If also is not empty, it is
+// another word for true, such as "explicit" for
+// itunes:explicit.
+func parseITunesBool(s, also string) bool {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "yes", "true":
+		return true
+	}
+	return also != "" && s == also
+}
+
+// parseITunesDuration parses an episode's length,
+// which is given as a number of seconds or as
+// HH:MM:SS or MM:SS. It returns 0 if s is invalid.
+func parseITunesDuration(s string) time.Duration {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+
+	var total float64
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0
+	}
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		// Only the seconds may be fractional.
+		if i < len(parts)-1 && n != float64(int64(n)) {
+			return 0
+		}
+		total = total*60 + n
+	}
+
+	return time.Duration(total * float64(time.Second))
+}
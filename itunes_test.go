@@ -0,0 +1,138 @@
+package rss
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseITunes(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/rss_2.0_podcast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Podcast{
+		Title:  "Hiking Treks Podcast",
+		Author: "The Sunset Explorers",
+		Image:  "https://applehosted.podcasts/hiking/artwork.jpg",
+		Owner:  &PodcastOwner{Name: "Sunset Explorers", Email: "mountainscape@icloud.com"},
+		Type:   "serial",
+		Categories: []*PodcastCategory{
+			{Name: "Sports", Subcategories: []string{"Wilderness"}},
+			{Name: "Leisure"},
+		},
+		Complete:   true,
+		NewFeedURL: "https://example.com/hiking/feed.xml",
+	}
+	if !reflect.DeepEqual(feed.Podcast, want) {
+		t.Errorf("got podcast %+v, want %+v", feed.Podcast, want)
+	}
+
+	// The iTunes elements fill in for missing
+	// standard ones, but do not replace them.
+	assertEqual("Hiking Treks", feed.Title, t)
+	assertEqual("The Sunset Explorers", feed.Author, t)
+	assertEqual("https://applehosted.podcasts/hiking/artwork.jpg", feed.Image.Href, t)
+	if !reflect.DeepEqual(feed.Categories, []string{"Outdoors"}) {
+		t.Errorf("got categories %q, want only the RSS category", feed.Categories)
+	}
+
+	if len(feed.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Items))
+	}
+	trailer, first := feed.Items[0], feed.Items[1]
+	assertEqual("Hiking Treks Trailer", trailer.Title, t)
+	if p := trailer.Podcast; p == nil || p.EpisodeType != "trailer" || p.Duration != 1079*time.Second || p.Explicit {
+		t.Errorf("got trailer %+v", p)
+	}
+
+	assertEqual("S01 EP01: Hiking Essentials", first.Title, t)
+	assertEqual("Jane Explorer", first.Author, t)
+	assertEqual("https://applehosted.podcasts/hiking/episode1.jpg", first.Image.Href, t)
+	want = &Podcast{
+		Title:       "Hiking Essentials",
+		Author:      "Jane Explorer",
+		Image:       "https://applehosted.podcasts/hiking/episode1.jpg",
+		Explicit:    true,
+		Block:       true,
+		Duration:    time.Hour + 2*time.Minute + 3500*time.Millisecond,
+		Season:      1,
+		Episode:     1,
+		EpisodeType: "full",
+	}
+	if !reflect.DeepEqual(first.Podcast, want) {
+		t.Errorf("got episode %+v, want %+v", first.Podcast, want)
+	}
+}
+
+func TestParseITunesAtom(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/atom_1.0_podcast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if feed.Podcast == nil || feed.Podcast.Type != "episodic" || len(feed.Podcast.Categories) != 1 {
+		t.Errorf("got podcast %+v", feed.Podcast)
+	}
+	assertEqual("Example Broadcasting", feed.Author, t)
+	if !reflect.DeepEqual(feed.Categories, []string{"News"}) {
+		t.Errorf("got categories %q", feed.Categories)
+	}
+
+	item := feed.Items[0]
+	assertEqual("Morning Show", item.Title, t)
+	assertEqual("Today's news, briefly.", item.Summary, t)
+	assertEqual("<p>Today's news.</p>", item.Content, t)
+	if p := item.Podcast; p == nil || p.Duration != 25*time.Minute || p.Episode != 12 || p.Title != "The Morning Show" {
+		t.Errorf("got episode %+v", p)
+	}
+}
+
+func TestParseITunesUndeclared(t *testing.T) {
+	feed := mustParse(t, `<rss version="2.0"><channel><title>Show</title>
+		<itunes:author>Someone</itunes:author>
+		<item><guid>1</guid><itunes:duration>90</itunes:duration></item>
+	</channel></rss>`)
+
+	if feed.Podcast == nil || feed.Podcast.Author != "Someone" {
+		t.Errorf("got podcast %+v", feed.Podcast)
+	}
+	if p := feed.Items[0].Podcast; p == nil || p.Duration != 90*time.Second {
+		t.Errorf("got episode %+v", p)
+	}
+	if feed := mustParse(t, `<rss version="2.0"><channel><item><guid>1</guid></item></channel></rss>`); feed.Podcast != nil || feed.Items[0].Podcast != nil {
+		t.Errorf("got podcast metadata for a plain feed")
+	}
+}
+
+func TestParseITunesDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":         0,
+		"3600":     time.Hour,
+		"90.5":     90*time.Second + 500*time.Millisecond,
+		"05:30":    5*time.Minute + 30*time.Second,
+		"1:02:03":  time.Hour + 2*time.Minute + 3*time.Second,
+		" 0:45 ":   45 * time.Second,
+		"1:2:3:4":  0,
+		"1.5:00":   0,
+		"-10":      0,
+		"an hour":  0,
+		"01:00:00": time.Hour,
+	}
+
+	for s, want := range tests {
+		if got := parseITunesDuration(s); got != want {
+			t.Errorf("%q: got %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func (feed *jsonFeed) checkVersion() error {
+	if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+		return fmt.Errorf("unsupported JSON Feed version %q", feed.Version)
+	}
+	return nil
+}
+
+// Feed returns the feed's metadata. It does
+// not include any items.
+func (feed *jsonFeed) Feed() *Feed {
+	out := new(Feed)
+	out.Title = feed.Title
+	out.Language = feed.Language
+	out.Author = feed.authors()
+	out.Description = feed.Description
+	out.Link = feed.HomePageURL
+	if feed.Icon != "" {
+		out.Image = &Image{Title: feed.Title, URL: feed.Icon}
+	} else if feed.Favicon != "" {
+		out.Image = &Image{Title: feed.Title, URL: feed.Favicon}
+	}
+	out.Extensions = feed.Extensions
+
+	return out
+}
+
+// Item converts item, returning nil if it
+// cannot be identified.
+func (item *jsonItem) Item() *Item {
+	id := item.ID.String()
+	if id == "" {
+		if item.URL == "" {
+			if debug {
+				fmt.Printf("[w] Item %q has no ID or URL and will be ignored.\n", item.Title)
+				fmt.Printf("[w] %#v\n", item)
+			}
+			return nil
+		}
+		id = item.URL
+	}
+
+	var err error
+	next := new(Item)
+	next.Title = item.Title
+	next.Summary = item.Summary
+	next.Content = item.ContentHTML
+	if next.Content == "" {
+		next.Content = item.ContentText
+	}
+	next.Categories = item.Tags
+	next.Link = item.URL
+	if next.Link == "" {
+		next.Link = item.ExternalURL
+	}
+	next.Author = item.authors()
+	if item.Image != "" {
+		next.Image = &Image{URL: item.Image}
+	} else if item.BannerImage != "" {
+		next.Image = &Image{URL: item.BannerImage}
+	}
+	if item.DatePublished != "" {
+		next.Date, err = parseTime(item.DatePublished)
+		if err == nil {
+			next.DateValid = true
+		}
+	} else if item.DateModified != "" {
+		next.Date, err = parseTime(item.DateModified)
+		if err == nil {
+			next.DateValid = true
+		}
+	}
+	next.ID = id
+	if len(item.Attachments) > 0 {
+		next.Enclosures = make([]*Enclosure, len(item.Attachments))
+		for i := range item.Attachments {
+			next.Enclosures[i] = item.Attachments[i].Enclosure()
+		}
+	}
+	next.Extensions = item.Extensions
+	next.Read = false
+
+	return next
+}
+
+// jsonExtensions returns the custom extension
+// objects in a JSON Feed object. Extension keys
+// start with an underscore.
+func jsonExtensions(data []byte) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+
+	var out map[string]json.RawMessage
+	for key, value := range fields {
+		if !strings.HasPrefix(key, "_") {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]json.RawMessage)
+		}
+		out[key] = value
+	}
+
+	return out
+}
+
+// addJSONExtensions adds the extension objects
+// in ext to the encoded JSON object in data.
+func addJSONExtensions(data []byte, ext map[string]json.RawMessage) ([]byte, error) {
+	if len(ext) == 0 {
+		return data, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range ext {
+		fields[key] = value
+	}
+
+	return json.Marshal(fields)
+}
+
+type jsonFeed struct {
+	Version     string                     `json:"version"`
+	Title       string                     `json:"title,omitempty"`
+	HomePageURL string                     `json:"home_page_url,omitempty"`
+	FeedURL     string                     `json:"feed_url,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Icon        string                     `json:"icon,omitempty"`
+	Favicon     string                     `json:"favicon,omitempty"`
+	Language    string                     `json:"language,omitempty"`
+	Author      *jsonAuthor                `json:"author,omitempty"`  // JSON Feed 1.0
+	Authors     []jsonAuthor               `json:"authors,omitempty"` // JSON Feed 1.1
+	Items       []jsonItem                 `json:"items"`
+	Extensions  map[string]json.RawMessage `json:"-"`
+}
+
+func (f *jsonFeed) UnmarshalJSON(data []byte) error {
+	type feed jsonFeed
+	if err := json.Unmarshal(data, (*feed)(f)); err != nil {
+		return err
+	}
+	f.Extensions = jsonExtensions(data)
+	return nil
+}
+
+func (f *jsonFeed) MarshalJSON() ([]byte, error) {
+	type feed jsonFeed
+	data, err := json.Marshal((*feed)(f))
+	if err != nil {
+		return nil, err
+	}
+	return addJSONExtensions(data, f.Extensions)
+}
+
+func (f *jsonFeed) authors() string {
+	return joinJSONAuthors(f.Author, f.Authors)
+}
+
+type jsonAuthor struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+func joinJSONAuthors(author *jsonAuthor, authors []jsonAuthor) string {
+	if len(authors) == 0 && author != nil {
+		authors = []jsonAuthor{*author}
+	}
+
+	names := make([]string, 0, len(authors))
+	for _, a := range authors {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		} else if a.URL != "" {
+			names = append(names, a.URL)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// jsonID holds an item ID. The specification
+// requires a string, but numbers are common
+// in the wild.
+type jsonID string
+
+func (j *jsonID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*j = jsonID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*j = jsonID(n.String())
+	return nil
+}
+
+func (j jsonID) String() string {
+	return string(j)
+}
+
+type jsonItem struct {
+	ID            jsonID                     `json:"id,omitempty"`
+	URL           string                     `json:"url,omitempty"`
+	ExternalURL   string                     `json:"external_url,omitempty"`
+	Title         string                     `json:"title,omitempty"`
+	ContentHTML   string                     `json:"content_html,omitempty"`
+	ContentText   string                     `json:"content_text,omitempty"`
+	Summary       string                     `json:"summary,omitempty"`
+	Image         string                     `json:"image,omitempty"`
+	BannerImage   string                     `json:"banner_image,omitempty"`
+	DatePublished string                     `json:"date_published,omitempty"`
+	DateModified  string                     `json:"date_modified,omitempty"`
+	Author        *jsonAuthor                `json:"author,omitempty"`  // JSON Feed 1.0
+	Authors       []jsonAuthor               `json:"authors,omitempty"` // JSON Feed 1.1
+	Tags          []string                   `json:"tags,omitempty"`
+	Attachments   []jsonAttachment           `json:"attachments,omitempty"`
+	Extensions    map[string]json.RawMessage `json:"-"`
+}
+
+func (i *jsonItem) UnmarshalJSON(data []byte) error {
+	type item jsonItem
+	if err := json.Unmarshal(data, (*item)(i)); err != nil {
+		return err
+	}
+	i.Extensions = jsonExtensions(data)
+	return nil
+}
+
+func (i *jsonItem) MarshalJSON() ([]byte, error) {
+	type item jsonItem
+	data, err := json.Marshal((*item)(i))
+	if err != nil {
+		return nil, err
+	}
+	return addJSONExtensions(data, i.Extensions)
+}
+
+func (i *jsonItem) authors() string {
+	return joinJSONAuthors(i.Author, i.Authors)
+}
+
+type jsonAttachment struct {
+	URL               string  `json:"url,omitempty"`
+	MIMEType          string  `json:"mime_type,omitempty"`
+	Title             string  `json:"title,omitempty"`
+	SizeInBytes       uint    `json:"size_in_bytes,omitempty"`
+	DurationInSeconds float64 `json:"duration_in_seconds,omitempty"`
+}
+
+func (j *jsonAttachment) Enclosure() *Enclosure {
+	out := new(Enclosure)
+	out.URL = j.URL
+	out.Type = j.MIMEType
+	out.Length = j.SizeInBytes
+	return out
+}
@@ -0,0 +1,146 @@
+package rss
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJSONFeedTitle(t *testing.T) {
+	tests := map[string]string{
+		"json_feed_1.0": "JSON Feed",
+		"json_feed_1.1": "My Example Feed",
+	}
+
+	for test, want := range tests {
+		name := filepath.Join("testdata", test)
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Reading %s: %v", name, err)
+		}
+
+		feed, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Parsing %s: %v", name, err)
+		}
+
+		if feed.Title != want {
+			t.Errorf("%s: got %q, want %q", name, feed.Title, want)
+		}
+	}
+}
+
+func TestParseJSONFeedChannel(t *testing.T) {
+	tests := []struct {
+		name     string
+		testdata string
+		verify   func(t *testing.T, feed *Feed)
+	}{{
+		name:     "version 1.0",
+		testdata: "json_feed_1.0",
+		verify: func(t *testing.T, feed *Feed) {
+			assertEqual("Brent Simmons and Manton Reece", feed.Author, t)
+			assertEqual("https://jsonfeed.org/", feed.Link, t)
+			assertEqual("https://jsonfeed.org/graphics/icon.png", feed.Image.URL, t)
+		},
+	}, {
+		name:     "version 1.1",
+		testdata: "json_feed_1.1",
+		verify: func(t *testing.T, feed *Feed) {
+			assertEqual("Jane Doe, John Doe", feed.Author, t)
+			assertEqual("en-GB", feed.Language, t)
+			assertEqual("A feed of examples.", feed.Description, t)
+			assertEqual("https://example.org/", feed.Link, t)
+			assertEqual("https://example.org/icon.png", feed.Image.URL, t)
+			assertEqual(`{"about": "https://example.org/extension", "level": 3}`, string(feed.Extensions["_example"]), t)
+			if len(feed.Extensions) != 1 {
+				t.Errorf("got extensions %q, want only _example", feed.Extensions)
+			}
+		},
+	}}
+	for i := range tests {
+		tt := tests[i]
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join("testdata", tt.testdata)
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				t.Fatalf("Reading %s: %v", name, err)
+			}
+
+			feed, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parsing %s: %v", name, err)
+			}
+			tt.verify(t, feed)
+		})
+	}
+}
+
+func TestParseJSONFeedItems(t *testing.T) {
+	name := filepath.Join("testdata", "json_feed_1.1")
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Reading %s: %v", name, err)
+	}
+
+	feed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parsing %s: %v", name, err)
+	}
+
+	if len(feed.Items) != 2 {
+		t.Fatalf("%s: got %d items, want 2", name, len(feed.Items))
+	}
+	if feed.Unread != 2 {
+		t.Errorf("%s: got %d unread, want 2", name, feed.Unread)
+	}
+
+	item := feed.Items[0]
+	assertEqual("2", item.ID, t)
+	assertEqual("Second item", item.Title, t)
+	assertEqual("The second item.", item.Summary, t)
+	assertEqual("<p>This is the <em>second</em> item.</p>", item.Content, t)
+	assertEqual("https://example.org/second-item", item.Link, t)
+	assertEqual("Jane Doe", item.Author, t)
+	assertEqual("https://example.org/second.png", item.Image.URL, t)
+	assertEqual(`{"rating": 5}`, string(item.Extensions["_example"]), t)
+	if want := []string{"example", "second"}; !reflect.DeepEqual(item.Categories, want) {
+		t.Errorf("%s: got categories %q, want %q", name, item.Categories, want)
+	}
+	if want := time.Date(2020, 8, 7, 16, 44, 36, 0, time.UTC); !item.DateValid || !item.Date.Equal(want) {
+		t.Errorf("%s: got date %v (valid: %v), want %v", name, item.Date, item.DateValid, want)
+	}
+	want := Enclosure{URL: "https://example.org/second.mp3", Type: "audio/mpeg", Length: 89970236}
+	if len(item.Enclosures) != 1 || !reflect.DeepEqual(*item.Enclosures[0], want) {
+		t.Errorf("%s: got enclosures %v, want %v", name, item.Enclosures, want)
+	}
+
+	item = feed.Items[1]
+	assertEqual("1", item.ID, t)
+	assertEqual("This is the first item.", item.Content, t)
+	assertEqual("https://example.com/linked", item.Link, t)
+	assertEqual("https://example.org/first-banner.png", item.Image.URL, t)
+	if want := time.Date(2020, 8, 6, 9, 0, 0, 0, time.UTC); !item.DateValid || !item.Date.Equal(want) {
+		t.Errorf("%s: got date %v (valid: %v), want %v", name, item.Date, item.DateValid, want)
+	}
+}
+
+func TestParseJSONFeedVersion(t *testing.T) {
+	if _, err := Parse([]byte(`{"version": "1.1", "items": []}`)); err == nil {
+		t.Error("expected error for unknown JSON Feed version, got none")
+	}
+}
+
+func TestParseJSONFeedNoIcon(t *testing.T) {
+	feed := mustParse(t, `{"version": "https://jsonfeed.org/version/1.1", "title": "No icon", "items": []}`)
+	if feed.Image != nil {
+		t.Errorf("got image %+v for a feed with no icon, want nil", feed.Image)
+	}
+
+	feed = mustParse(t, `{"version": "https://jsonfeed.org/version/1.1", "title": "Favicon", "favicon": "https://example.org/favicon.ico", "items": []}`)
+	if feed.Image == nil || feed.Image.URL != "https://example.org/favicon.ico" {
+		t.Errorf("got image %+v, want the favicon", feed.Image)
+	}
+}
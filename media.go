@@ -0,0 +1,315 @@
+package rss
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nsMedia is the namespace of the Media RSS
+// elements, used by YouTube, Flickr and many
+// news sites.
+const nsMedia = "http://search.yahoo.com/mrss/"
+
+// Media holds the Media RSS metadata of an item.
+// Elements given directly in the item are in the
+// embedded MediaGroup.
+type Media struct {
+	MediaGroup
+	Groups []*MediaGroup `json:"groups,omitempty"` // Each holds renditions of the same media, such as at different sizes.
+}
+
+// MediaGroup is a set of media objects with the
+// details that apply to all of them.
+type MediaGroup struct {
+	Contents []*MediaContent `json:"contents,omitempty"`
+	MediaDetails
+}
+
+// MediaDetails describes a media object or a
+// group of them.
+type MediaDetails struct {
+	Thumbnails  []*MediaThumbnail `json:"thumbnails,omitempty"`
+	Player      *MediaPlayer      `json:"player,omitempty"`
+	Credits     []*MediaCredit    `json:"credits,omitempty"`
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+// MediaContent is a media object, such as a
+// video or an image, or one rendition of it.
+type MediaContent struct {
+	URL        string        `json:"url,omitempty"` // Empty if the content is only available through its player.
+	Type       string        `json:"type,omitempty"`
+	Medium     string        `json:"medium,omitempty"` // Such as "image", "audio" or "video".
+	FileSize   uint          `json:"filesize,omitempty"`
+	Bitrate    float64       `json:"bitrate,omitempty"` // In kilobits per second.
+	Duration   time.Duration `json:"duration,omitempty"`
+	Width      int           `json:"width,omitempty"`
+	Height     int           `json:"height,omitempty"`
+	Lang       string        `json:"lang,omitempty"`
+	Expression string        `json:"expression,omitempty"` // "full", "sample" or "nonstop".
+	IsDefault  bool          `json:"isdefault,omitempty"`  // Whether this is the default rendition in its group.
+	MediaDetails
+}
+
+// Enclosure returns the content as an Enclosure,
+// so that it can be fetched in the same way.
+func (c *MediaContent) Enclosure() *Enclosure {
+	return &Enclosure{URL: c.URL, Type: c.Type, Length: c.FileSize}
+}
+
+// MediaThumbnail is a picture of a media object.
+type MediaThumbnail struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// MediaPlayer is a web page from which a media
+// object can be played, such as for embedding.
+type MediaPlayer struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// MediaCredit names someone who took part in
+// making a media object.
+type MediaCredit struct {
+	Name   string `json:"name"`
+	Role   string `json:"role,omitempty"`   // Such as "author" or "photographer".
+	Scheme string `json:"scheme,omitempty"` // Defines the roles. Defaults to the European Broadcasting Union's.
+}
+
+// Thumbnail returns the largest thumbnail given
+// anywhere in m, or nil if there are none. Those
+// without a size are only chosen if none have one.
+func (m *Media) Thumbnail() *MediaThumbnail {
+	var best *MediaThumbnail
+	consider := func(thumbnails []*MediaThumbnail) {
+		for _, t := range thumbnails {
+			if best == nil || t.Width*t.Height > best.Width*best.Height {
+				best = t
+			}
+		}
+	}
+	for _, group := range m.groups() {
+		consider(group.Thumbnails)
+		for _, content := range group.Contents {
+			consider(content.Thumbnails)
+		}
+	}
+
+	return best
+}
+
+// image returns the best picture for an item: the
+// largest thumbnail or, failing that, the largest
+// image content. It returns nil if there is none.
+func (m *Media) image() *Image {
+	if t := m.Thumbnail(); t != nil {
+		return &Image{URL: t.URL, Width: uint32(t.Width), Height: uint32(t.Height)}
+	}
+
+	var best *MediaContent
+	for _, group := range m.groups() {
+		for _, content := range group.Contents {
+			if content.URL == "" || content.Medium != "image" && !strings.HasPrefix(content.Type, "image/") {
+				continue
+			}
+			if best == nil || content.Width*content.Height > best.Width*best.Height {
+				best = content
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &Image{URL: best.URL, Title: best.Title, Width: uint32(best.Width), Height: uint32(best.Height)}
+}
+
+// fill sets item's image and summary from m
+// where the item has none of its own.
+func (m *Media) fill(item *Item) {
+	if item.Image == nil || item.Image.URL == "" && item.Image.Href == "" {
+		if img := m.image(); img != nil {
+			item.Image = img
+		}
+	}
+	if item.Summary == "" {
+		item.Summary = m.description()
+	}
+}
+
+// description returns the first description
+// given anywhere in m.
+func (m *Media) description() string {
+	for _, group := range m.groups() {
+		if group.Description != "" {
+			return group.Description
+		}
+		for _, content := range group.Contents {
+			if content.Description != "" {
+				return content.Description
+			}
+		}
+	}
+	return ""
+}
+
+// groups returns the item's own elements and its
+// groups, in that order.
+func (m *Media) groups() []*MediaGroup {
+	return append([]*MediaGroup{&m.MediaGroup}, m.Groups...)
+}
+
+// mediaElements holds the elements in the Media
+// RSS namespace of an item.
+type mediaElements struct {
+	mediaGroupElements
+	Groups []mediaGroupElements `xml:"group"`
+}
+
+type mediaGroupElements struct {
+	Contents []mediaContent `xml:"content"`
+	mediaDetails
+}
+
+// mediaDetails holds the elements that can be
+// given for a group or for a single content.
+type mediaDetails struct {
+	Thumbnails  []mediaThumbnail `xml:"thumbnail"`
+	Player      *mediaPlayer     `xml:"player"`
+	Credits     []mediaCredit    `xml:"credit"`
+	Title       string           `xml:"title"`
+	Description string           `xml:"description"`
+}
+
+type mediaContent struct {
+	URL        string `xml:"url,attr"`
+	Type       string `xml:"type,attr"`
+	Medium     string `xml:"medium,attr"`
+	FileSize   string `xml:"fileSize,attr"`
+	Bitrate    string `xml:"bitrate,attr"`
+	Duration   string `xml:"duration,attr"`
+	Width      string `xml:"width,attr"`
+	Height     string `xml:"height,attr"`
+	Lang       string `xml:"lang,attr"`
+	Expression string `xml:"expression,attr"`
+	IsDefault  string `xml:"isDefault,attr"`
+	mediaDetails
+}
+
+type mediaThumbnail struct {
+	URL    string `xml:"url,attr"`
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+}
+
+type mediaPlayer struct {
+	URL    string `xml:"url,attr"`
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+}
+
+type mediaCredit struct {
+	Role   string `xml:"role,attr"`
+	Scheme string `xml:"scheme,attr"`
+	Name   string `xml:",chardata"`
+}
+
+// register adds m to ext for the Media RSS
+// namespace, including feeds that use the media
+// prefix without declaring it.
+func (m *mediaElements) register(ext map[string]interface{}) {
+	ext[nsMedia] = m
+	ext["media"] = m
+}
+
+// Media returns the Media RSS metadata, or nil if
+// there was none.
+func (m *mediaElements) Media() *Media {
+	out := &Media{MediaGroup: *m.mediaGroupElements.group()}
+	for i := range m.Groups {
+		if group := m.Groups[i].group(); !reflect.DeepEqual(group, &MediaGroup{}) {
+			out.Groups = append(out.Groups, group)
+		}
+	}
+
+	if reflect.DeepEqual(out, &Media{}) {
+		return nil
+	}
+	return out
+}
+
+func (g *mediaGroupElements) group() *MediaGroup {
+	out := &MediaGroup{MediaDetails: g.mediaDetails.details()}
+	for _, c := range g.Contents {
+		next := &MediaContent{
+			URL:          strings.TrimSpace(c.URL),
+			Type:         strings.TrimSpace(c.Type),
+			Medium:       strings.ToLower(strings.TrimSpace(c.Medium)),
+			Duration:     parseITunesDuration(c.Duration),
+			Width:        parseMediaInt(c.Width),
+			Height:       parseMediaInt(c.Height),
+			Lang:         strings.TrimSpace(c.Lang),
+			Expression:   strings.ToLower(strings.TrimSpace(c.Expression)),
+			IsDefault:    parseITunesBool(c.IsDefault, ""),
+			MediaDetails: c.mediaDetails.details(),
+		}
+		if n, err := strconv.ParseUint(strings.TrimSpace(c.FileSize), 10, 0); err == nil {
+			next.FileSize = uint(n)
+		}
+		next.Bitrate, _ = strconv.ParseFloat(strings.TrimSpace(c.Bitrate), 64)
+		if next.URL != "" || next.Player != nil {
+			out.Contents = append(out.Contents, next)
+		}
+	}
+
+	return out
+}
+
+func (d *mediaDetails) details() MediaDetails {
+	var out MediaDetails
+	for _, t := range d.Thumbnails {
+		if url := strings.TrimSpace(t.URL); url != "" {
+			out.Thumbnails = append(out.Thumbnails, &MediaThumbnail{
+				URL:    url,
+				Width:  parseMediaInt(t.Width),
+				Height: parseMediaInt(t.Height),
+			})
+		}
+	}
+	if d.Player != nil && strings.TrimSpace(d.Player.URL) != "" {
+		out.Player = &MediaPlayer{
+			URL:    strings.TrimSpace(d.Player.URL),
+			Width:  parseMediaInt(d.Player.Width),
+			Height: parseMediaInt(d.Player.Height),
+		}
+	}
+	for _, c := range d.Credits {
+		if name := strings.TrimSpace(c.Name); name != "" {
+			out.Credits = append(out.Credits, &MediaCredit{
+				Name:   name,
+				Role:   strings.ToLower(strings.TrimSpace(c.Role)),
+				Scheme: strings.TrimSpace(c.Scheme),
+			})
+		}
+	}
+	out.Title = strings.TrimSpace(d.Title)
+	out.Description = strings.TrimSpace(d.Description)
+
+	return out
+}
+
+// parseMediaInt parses a size, returning 0 if s
+// is invalid.
+func parseMediaInt(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,152 @@
+package rss
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMedia(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/rss_2.0_media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Items))
+	}
+
+	// An item with several images uses the
+	// largest as its image.
+	park := feed.Items[0]
+	want := &Media{MediaGroup: MediaGroup{Contents: []*MediaContent{
+		{URL: "https://news.example.com/img/park-small.jpg", Medium: "image", Width: 320, Height: 180},
+		{
+			URL:    "https://news.example.com/img/park-large.jpg",
+			Medium: "image",
+			Width:  1280,
+			Height: 720,
+			MediaDetails: MediaDetails{
+				Title:   "The new park at dawn",
+				Credits: []*MediaCredit{{Name: "Sam Lee", Role: "photographer", Scheme: "urn:ebu"}},
+			},
+		},
+	}}}
+	if !reflect.DeepEqual(park.Media, want) {
+		t.Errorf("got media %+v, want %+v", park.Media, want)
+	}
+	wantImage := &Image{URL: "https://news.example.com/img/park-large.jpg", Title: "The new park at dawn", Width: 1280, Height: 720}
+	if !reflect.DeepEqual(park.Image, wantImage) {
+		t.Errorf("got image %+v, want %+v", park.Image, wantImage)
+	}
+	assertEqual("The park opened on Saturday.", park.Summary, t)
+
+	council := feed.Items[1]
+	want = &Media{
+		MediaGroup: MediaGroup{MediaDetails: MediaDetails{
+			Thumbnails: []*MediaThumbnail{{URL: "https://news.example.com/img/unsized.jpg"}},
+		}},
+		Groups: []*MediaGroup{{
+			Contents: []*MediaContent{
+				{
+					URL:        "https://news.example.com/video/council-1080.mp4",
+					Type:       "video/mp4",
+					Medium:     "video",
+					FileSize:   1031625000,
+					Bitrate:    4500,
+					Duration:   1834 * time.Second,
+					Width:      1920,
+					Height:     1080,
+					Expression: "full",
+				},
+				{
+					URL:       "https://news.example.com/video/council-480.mp4",
+					Type:      "video/mp4",
+					Medium:    "video",
+					Bitrate:   1200.5,
+					Duration:  1834 * time.Second,
+					Width:     854,
+					Height:    480,
+					IsDefault: true,
+				},
+			},
+			MediaDetails: MediaDetails{
+				Thumbnails: []*MediaThumbnail{
+					{URL: "https://news.example.com/img/council-thumb-small.jpg", Width: 160, Height: 90},
+					{URL: "https://news.example.com/img/council-thumb.jpg", Width: 640, Height: 360},
+				},
+				Player:      &MediaPlayer{URL: "https://news.example.com/player?v=council", Width: 640, Height: 360},
+				Credits:     []*MediaCredit{{Name: "Newsroom", Role: "producer"}},
+				Description: "Full video of the council meeting.",
+			},
+		}},
+	}
+	if !reflect.DeepEqual(council.Media, want) {
+		t.Errorf("got media %+v, want %+v", council.Media, want)
+	}
+
+	// Thumbnails are preferred to image content,
+	// and sized ones to those without a size.
+	wantImage = &Image{URL: "https://news.example.com/img/council-thumb.jpg", Width: 640, Height: 360}
+	if !reflect.DeepEqual(council.Image, wantImage) {
+		t.Errorf("got image %+v, want %+v", council.Image, wantImage)
+	}
+	assertEqual("Full video of the council meeting.", council.Summary, t)
+
+	enclosure := council.Media.Groups[0].Contents[1].Enclosure()
+	if *enclosure != (Enclosure{URL: "https://news.example.com/video/council-480.mp4", Type: "video/mp4"}) {
+		t.Errorf("got enclosure %+v", enclosure)
+	}
+}
+
+func TestParseMediaAtom(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/atom_1.0_media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := feed.Items[0]
+	assertEqual("Building a Trail Map", item.Title, t)
+	assertEqual("How we surveyed the ridge trail, step by step.", item.Summary, t)
+	wantImage := &Image{URL: "https://i1.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg", Width: 480, Height: 360}
+	if !reflect.DeepEqual(item.Image, wantImage) {
+		t.Errorf("got image %+v, want %+v", item.Image, wantImage)
+	}
+	if m := item.Media; m == nil || len(m.Groups) != 1 || len(m.Groups[0].Contents) != 1 || m.Groups[0].Title != "Building a Trail Map" {
+		t.Errorf("got media %+v", m)
+	}
+}
+
+func TestParseMediaOtherImage(t *testing.T) {
+	feed := mustParse(t, `<rss version="2.0"><channel>
+		<item><guid>1</guid><image><url>http://example.com/own.jpg</url></image>
+			<media:thumbnail url="http://example.com/thumb.jpg"/></item>
+		<item><guid>2</guid></item>
+	</channel></rss>`)
+
+	// The item's own image is kept, and the
+	// undeclared media prefix is understood.
+	assertEqual("http://example.com/own.jpg", feed.Items[0].Image.URL, t)
+	if m := feed.Items[0].Media; m == nil || m.Thumbnail() == nil || m.Thumbnail().URL != "http://example.com/thumb.jpg" {
+		t.Errorf("got media %+v", m)
+	}
+	if feed.Items[1].Media != nil {
+		t.Errorf("got media for a plain item")
+	}
+
+	feed = mustParse(t, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:media="http://search.yahoo.com/mrss/">
+		<channel><title>RDF</title></channel>
+		<item><link>http://example.com/1</link><media:thumbnail url="http://example.com/thumb.jpg" width="100" height="100"/></item>
+	</rdf:RDF>`)
+	if img := feed.Items[0].Image; img == nil || img.URL != "http://example.com/thumb.jpg" {
+		t.Errorf("got image %+v for an RSS 1.0 item", img)
+	}
+}
@@ -0,0 +1,178 @@
+package rss
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"reflect"
+)
+
+// Changes describes how an update changed a feed.
+type Changes struct {
+	Metadata []string // Names of the Feed fields that changed, such as "Title".
+	Added    []string // IDs of new items.
+	Modified []string // IDs of items that had been edited.
+	Removed  []string // IDs of items no longer in the feed's document.
+	Pruned   []string // IDs of items removed by the feed's Retention.
+
+	added []*Item
+}
+
+// Empty reports whether nothing changed.
+func (c *Changes) Empty() bool {
+	return len(c.Metadata) == 0 && len(c.Added) == 0 && len(c.Modified) == 0 &&
+		len(c.Removed) == 0 && len(c.Pruned) == 0
+}
+
+// Merge merges update, a newer copy of the feed
+// such as one returned by Fetch, into f. The feed's
+// metadata, such as its title, link and image, is
+// replaced by update's. New items are added, and
+// items that have been edited are replaced, keeping
+// their Read and Starred state. Items are never
+// changed once they are in f: an edited item is a
+// new *Item in f.Items. Items that are no
+// longer in update are kept, as most feeds only
+// include their latest items, but are listed in the
+// changes. Items that f has pruned (see Retention)
+// are not added again.
+func (f *Feed) Merge(update *Feed) *Changes {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.buildItemMap()
+	return f.merge(update)
+}
+
+// merge is like Merge, but must be called with
+// f.mu held and f.ItemMap built.
+func (f *Feed) merge(update *Feed) *Changes {
+	f.Redirect = update.Redirect
+	if update.Redirect != nil && update.Redirect.Permanent {
+		f.UpdateURL = update.Redirect.URL
+	}
+
+	f.ETag = update.ETag
+	f.LastModified = update.LastModified
+
+	changes := new(Changes)
+	changes.Metadata = f.mergeMetadata(update)
+
+	stored := make(map[string]int, len(f.Items))
+	for i, item := range f.Items {
+		stored[item.ID] = i
+	}
+
+	pruned := make(map[string]bool, len(f.Seen))
+	for _, id := range f.Seen {
+		pruned[id] = true
+	}
+
+	seen := make(map[string]bool, len(update.Items))
+	for _, item := range update.Items {
+		if seen[item.ID] || pruned[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+
+		if _, ok := f.ItemMap[item.ID]; !ok {
+			f.Items = append(f.Items, item)
+			f.ItemMap[item.ID] = struct{}{}
+			f.Unread++
+			changes.Added = append(changes.Added, item.ID)
+			changes.added = append(changes.added, item)
+			continue
+		}
+
+		i, ok := stored[item.ID]
+		if !ok || f.Items[i].fingerprint() == item.fingerprint() {
+			continue
+		}
+
+		// Replace the item rather than editing it,
+		// as callers may be reading the old one.
+		item.Read, item.Starred = f.Items[i].Read, f.Items[i].Starred
+		f.Items[i] = item
+		changes.Modified = append(changes.Modified, item.ID)
+	}
+
+	for _, item := range f.Items {
+		if !seen[item.ID] {
+			changes.Removed = append(changes.Removed, item.ID)
+		}
+	}
+
+	return changes
+}
+
+// mergeMetadata copies the fields that describe
+// the feed itself from update into f, returning
+// the names of those that changed.
+func (f *Feed) mergeMetadata(update *Feed) []string {
+	var changed []string
+	merge := func(name string, old, new interface{}, copy func()) {
+		if !sameMetadata(old, new) {
+			copy()
+			changed = append(changed, name)
+		}
+	}
+
+	merge("Format", f.Format, update.Format, func() { f.Format = update.Format })
+	merge("Title", f.Title, update.Title, func() { f.Title = update.Title })
+	merge("Language", f.Language, update.Language, func() { f.Language = update.Language })
+	merge("Author", f.Author, update.Author, func() { f.Author = update.Author })
+	merge("Description", f.Description, update.Description, func() { f.Description = update.Description })
+	merge("Link", f.Link, update.Link, func() { f.Link = update.Link })
+	merge("Image", f.Image, update.Image, func() { f.Image = update.Image })
+	merge("Categories", f.Categories, update.Categories, func() { f.Categories = update.Categories })
+	merge("Podcast", f.Podcast, update.Podcast, func() { f.Podcast = update.Podcast })
+	merge("PodcastIndex", f.PodcastIndex, update.PodcastIndex, func() { f.PodcastIndex = update.PodcastIndex })
+	merge("DublinCore", f.DublinCore, update.DublinCore, func() { f.DublinCore = update.DublinCore })
+	merge("TTL", f.TTL, update.TTL, func() { f.TTL = update.TTL })
+	merge("SkipHours", f.SkipHours, update.SkipHours, func() { f.SkipHours = update.SkipHours })
+	merge("SkipDays", f.SkipDays, update.SkipDays, func() { f.SkipDays = update.SkipDays })
+	merge("Extensions", f.Extensions, update.Extensions, func() { f.Extensions = update.Extensions })
+
+	return changed
+}
+
+// sameMetadata reports whether old and new are
+// equal. Empty and nil lists are treated as the
+// same.
+func sameMetadata(old, new interface{}) bool {
+	o, n := reflect.ValueOf(old), reflect.ValueOf(new)
+	switch o.Kind() {
+	case reflect.Slice, reflect.Map:
+		if o.Len() == 0 && n.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(old, new)
+}
+
+// fingerprint returns a hash of the item's
+// content, ignoring its read and starred state.
+func (i *Item) fingerprint() [sha256.Size]byte {
+	c := *i
+	c.Read, c.Starred = false, false
+	data, err := json.Marshal(&c)
+	if err != nil {
+		// Only possible with extensions that are
+		// not valid JSON, which cannot be parsed.
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(data)
+}
+
+// buildItemMap makes f.ItemMap if it is missing,
+// as it is for a feed made by hand. It must be
+// called with f.mu held.
+func (f *Feed) buildItemMap() {
+	if f.ItemMap != nil {
+		return
+	}
+
+	f.ItemMap = make(map[string]struct{})
+	for _, item := range f.Items {
+		f.ItemMap[item.ID] = struct{}{}
+	}
+}
@@ -0,0 +1,285 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, data string) *Feed {
+	t.Helper()
+	f, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestMergeChanges(t *testing.T) {
+	feed := mustParse(t, `<feed xmlns="http://www.w3.org/2005/Atom">
+		<entry><id>1</id><title>First</title><updated>2022-03-14T09:00:00Z</updated></entry>
+		<entry><id>2</id><title>Secnod</title><updated>2022-03-14T10:00:00Z</updated></entry>
+		<entry><id>3</id><title>Third</title><updated>2022-03-14T11:00:00Z</updated></entry>
+		<entry><id>4</id><title>Fourth</title><updated>2022-03-14T12:00:00Z</updated></entry>
+	</feed>`)
+	feed.MarkRead("2")
+	feed.MarkRead("3")
+	second := feed.Items[1]
+
+	update := mustParse(t, `<feed xmlns="http://www.w3.org/2005/Atom">
+		<entry><id>5</id><title>Fifth</title><updated>2022-03-14T13:00:00Z</updated></entry>
+		<entry><id>4</id><title>Fourth</title><updated>2022-03-14T12:00:00Z</updated></entry>
+		<entry><id>3</id><title>Third</title><updated>2022-03-15T08:00:00Z</updated></entry>
+		<entry><id>2</id><title>Second</title><updated>2022-03-14T10:00:00Z</updated></entry>
+	</feed>`)
+
+	changes := feed.Merge(update)
+	want := &Changes{
+		Added:    []string{"5"},
+		Modified: []string{"3", "2"},
+		Removed:  []string{"1"},
+	}
+	if !reflect.DeepEqual(changes.Added, want.Added) || !reflect.DeepEqual(changes.Modified, want.Modified) || !reflect.DeepEqual(changes.Removed, want.Removed) {
+		t.Errorf("got changes %+v, want %+v", changes, want)
+	}
+
+	// Edited items are replaced, not changed, so
+	// one held from before the merge is unchanged.
+	assertEqual("Secnod", second.Title, t)
+	assertEqual("Second", feed.Items[1].Title, t)
+	if !feed.Items[1].Read {
+		t.Errorf("edited item lost its read state")
+	}
+	if len(feed.Items) != 5 || feed.Unread != 3 {
+		t.Errorf("got %d items and %d unread, want 5 and 3", len(feed.Items), feed.Unread)
+	}
+
+	if changes := feed.Merge(update); len(changes.Added) != 0 || len(changes.Modified) != 0 {
+		t.Errorf("merging the same update twice gave changes %+v", changes)
+	}
+}
+
+func TestUpdateItemsRace(t *testing.T) {
+	// Run with -race. Items returned by a feed are
+	// read without a lock while it is updated.
+	var version int32
+	fetch := func(url string) (*http.Response, error) {
+		doc := fmt.Sprintf(`<rss version="2.0"><channel><ttl>-1</ttl>
+			<item><guid>1</guid><title>Version %d</title></item>
+			<item><guid>2</guid><title>Version %[1]d</title></item>
+		</channel></rss>`, atomic.AddInt32(&version, 1))
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(doc))}, nil
+	}
+	feed, err := FetchByFunc(fetch, "http://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := feed.UnreadItems()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			for _, item := range items {
+				_ = item.Title + item.ID
+				_ = item.Read || item.Starred
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		feed.mu.Lock()
+		feed.Refresh = time.Time{}
+		feed.mu.Unlock()
+		if err := feed.UpdateByFunc(fetch); err != nil {
+			t.Fatal(err)
+		}
+		feed.MarkRead("1")
+		feed.SetStarred("2", i%2 == 0)
+	}
+	feed.MarkAllRead()
+	close(done)
+	wg.Wait()
+
+	assertEqual("Version 1", items[0].Title, t)
+	if items[0].Read {
+		t.Errorf("an item already returned was changed")
+	}
+}
+
+func TestMarshalJSONRace(t *testing.T) {
+	// Run with -race. A feed is marshalled while
+	// it is updated.
+	var version int32
+	fetch := func(url string) (*http.Response, error) {
+		doc := fmt.Sprintf(`<rss version="2.0"><channel><ttl>-1</ttl><title>Version %d</title>
+			<item><guid>%[1]d</guid></item>
+		</channel></rss>`, atomic.AddInt32(&version, 1))
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(doc))}, nil
+	}
+	feed, err := FetchByFunc(fetch, "http://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			feed.mu.Lock()
+			feed.Refresh = time.Time{}
+			feed.mu.Unlock()
+			if err := feed.UpdateByFunc(fetch); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		if _, err := json.Marshal(feed); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-done:
+			wg.Wait()
+			return
+		default:
+		}
+	}
+}
+
+func TestMergeEnclosures(t *testing.T) {
+	feed := mustParse(t, `<rss version="2.0"><channel>
+		<item><guid>1</guid><title>Episode</title></item>
+	</channel></rss>`)
+	update := mustParse(t, `<rss version="2.0"><channel>
+		<item><guid>1</guid><title>Episode</title><enclosure url="http://example.com/1.mp3" length="100" type="audio/mpeg"/></item>
+	</channel></rss>`)
+
+	changes := feed.Merge(update)
+	if !reflect.DeepEqual(changes.Modified, []string{"1"}) || changes.Empty() {
+		t.Errorf("got changes %+v, want item 1 modified", changes)
+	}
+	if len(feed.Items[0].Enclosures) != 1 {
+		t.Errorf("enclosure was not merged")
+	}
+
+	if !new(Changes).Empty() {
+		t.Errorf("new Changes is not empty")
+	}
+}
+
+func TestUpdateChanges(t *testing.T) {
+	doc := `<rss version="2.0"><channel><ttl>-1</ttl>
+		<item><guid>1</guid><title>Typo</title></item>
+	</channel></rss>`
+	fetch := func(url string) (*http.Response, error) {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(doc))}, nil
+	}
+
+	feed, err := FetchByFunc(fetch, "http://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.MarkAllRead()
+
+	doc = strings.Replace(doc, "Typo", "Fixed", 1)
+	changes, err := feed.UpdateChanges(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(changes.Modified, []string{"1"}) || len(changes.Added) != 0 {
+		t.Errorf("got changes %+v, want item 1 modified", changes)
+	}
+	assertEqual("Fixed", feed.Items[0].Title, t)
+	if !feed.Items[0].Read || feed.Unread != 0 {
+		t.Errorf("edited item lost its read state")
+	}
+}
+
+func TestMergeMetadata(t *testing.T) {
+	feed := mustParse(t, `<rss version="2.0"><channel>
+		<title>Old name</title>
+		<link>http://example.com/</link>
+		<description>A blog</description>
+		<item><guid>1</guid></item>
+	</channel></rss>`)
+	update := mustParse(t, `<rss version="2.0"><channel>
+		<title>New name</title>
+		<link>http://example.org/</link>
+		<description>A blog</description>
+		<language>en-gb</language>
+		<category>News</category>
+		<image><url>http://example.org/icon.png</url><title>New name</title><link>http://example.org/</link></image>
+		<item><guid>1</guid></item>
+	</channel></rss>`)
+
+	changes := feed.Merge(update)
+	want := []string{"Title", "Language", "Link", "Image", "Categories"}
+	if !reflect.DeepEqual(changes.Metadata, want) {
+		t.Errorf("got metadata changes %q, want %q", changes.Metadata, want)
+	}
+
+	assertEqual("New name", feed.Title, t)
+	assertEqual("http://example.org/", feed.Link, t)
+	assertEqual("en-gb", feed.Language, t)
+	assertEqual("http://example.org/icon.png", feed.Image.URL, t)
+	if !reflect.DeepEqual(feed.Categories, []string{"News"}) {
+		t.Errorf("got categories %q", feed.Categories)
+	}
+
+	if changes := feed.Merge(update); !changes.Empty() {
+		t.Errorf("merging the same update twice gave changes %+v", changes)
+	}
+}
+
+func TestMergeAllMetadata(t *testing.T) {
+	update := &Feed{
+		Format:       FormatAtom,
+		Title:        "Title",
+		Language:     "en",
+		Author:       "Author",
+		Description:  "Description",
+		Link:         "http://example.com/",
+		Image:        &Image{URL: "http://example.com/icon.png"},
+		Categories:   []string{"News"},
+		Podcast:      &Podcast{Type: "serial"},
+		PodcastIndex: &PodcastIndex{Locked: &PodcastLocked{}},
+		DublinCore:   &DublinCore{Rights: "Rights"},
+		TTL:          time.Hour,
+		SkipHours:    []int{1},
+		SkipDays:     []time.Weekday{time.Sunday},
+		Extensions:   map[string]json.RawMessage{"_x": json.RawMessage(`1`)},
+	}
+
+	feed := new(Feed)
+	changes := feed.Merge(update)
+	want := []string{"Format", "Title", "Language", "Author", "Description", "Link", "Image", "Categories",
+		"Podcast", "PodcastIndex", "DublinCore", "TTL", "SkipHours", "SkipDays", "Extensions"}
+	if !reflect.DeepEqual(changes.Metadata, want) {
+		t.Errorf("got metadata changes %q, want %q", changes.Metadata, want)
+	}
+	if changes := feed.Merge(update); !changes.Empty() {
+		t.Errorf("merging the same update twice gave changes %+v", changes)
+	}
+}
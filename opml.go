@@ -0,0 +1,253 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// formatOPML is used in a ParseError for an
+// OPML document.
+const formatOPML Format = "OPML"
+
+// OPML is an outline document, as used by feed
+// readers to exchange lists of subscriptions.
+type OPML struct {
+	Version      string
+	Title        string
+	DateCreated  time.Time // Zero if not given or invalid.
+	DateModified time.Time // Zero if not given or invalid.
+	OwnerName    string
+	OwnerEmail   string
+	Outlines     []*Outline
+}
+
+// An Outline is an entry in an OPML document. A
+// subscription has an XMLURL, and a folder holds
+// further Outlines.
+type Outline struct {
+	Text       string
+	Title      string
+	Type       string // Usually "rss" for subscriptions, whatever their format.
+	XMLURL     string // URL of the feed.
+	HTMLURL    string // URL of the feed's website.
+	Categories []string
+
+	// Attrs holds any other attributes, in the
+	// order they appeared, so that they can be
+	// written back out.
+	Attrs []xml.Attr
+
+	Outlines []*Outline
+}
+
+// ParseOPML reads an OPML document from r.
+func ParseOPML(r io.Reader) (*OPML, error) {
+	rec := &recorder{r: r}
+	d := xml.NewDecoder(rec)
+	d.CharsetReader = charsetReader
+
+	var root xml.StartElement
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: no root element", ErrUnknownFormat)
+		}
+		if err != nil {
+			return nil, rec.parseError(formatOPML, d.InputOffset(), err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root = start
+			break
+		}
+	}
+	if root.Name.Local != "opml" {
+		return nil, fmt.Errorf("%w: root element <%s>, want <opml>", ErrUnknownFormat, root.Name.Local)
+	}
+
+	var doc opmlDoc
+	if err := d.DecodeElement(&doc, &root); err != nil {
+		return nil, rec.parseError(formatOPML, d.InputOffset(), err)
+	}
+
+	o := &OPML{
+		Version:    doc.Version,
+		Title:      strings.TrimSpace(doc.Head.Title),
+		OwnerName:  strings.TrimSpace(doc.Head.OwnerName),
+		OwnerEmail: strings.TrimSpace(doc.Head.OwnerEmail),
+		Outlines:   doc.Body.Outlines,
+	}
+	if t, err := parseTime(doc.Head.DateCreated); err == nil {
+		o.DateCreated = t
+	}
+	if t, err := parseTime(doc.Head.DateModified); err == nil {
+		o.DateModified = t
+	}
+
+	return o, nil
+}
+
+// WriteOPML writes an OPML document to w listing
+// feeds, using their Nickname if they have one,
+// or else their Title, as the outline's text.
+func WriteOPML(w io.Writer, feeds []*Feed) error {
+	o := &OPML{Title: "Subscriptions", DateCreated: time.Now()}
+	for _, f := range feeds {
+		f.mu.RLock()
+		text := f.Nickname
+		if text == "" {
+			text = f.Title
+		}
+		o.Outlines = append(o.Outlines, &Outline{
+			Text:    text,
+			Title:   f.Title,
+			Type:    "rss",
+			XMLURL:  f.UpdateURL,
+			HTMLURL: f.Link,
+		})
+		f.mu.RUnlock()
+	}
+
+	return o.Write(w)
+}
+
+// Write writes o to w as an OPML 2.0 document.
+func (o *OPML) Write(w io.Writer) error {
+	doc := opmlDoc{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:      o.Title,
+			OwnerName:  o.OwnerName,
+			OwnerEmail: o.OwnerEmail,
+		},
+		Body: opmlBody{Outlines: o.Outlines},
+	}
+	if !o.DateCreated.IsZero() {
+		doc.Head.DateCreated = o.DateCreated.Format(time.RFC1123Z)
+	}
+	if !o.DateModified.IsZero() {
+		doc.Head.DateModified = o.DateModified.Format(time.RFC1123Z)
+	}
+
+	return writeXML(w, doc)
+}
+
+// Feeds returns a Feed for each subscription in
+// o, including those in folders, with its Nickname,
+// Title, Link and UpdateURL set from the outline.
+// The feeds can then be fetched with Update.
+func (o *OPML) Feeds() []*Feed {
+	var feeds []*Feed
+	var walk func(outlines []*Outline)
+	walk = func(outlines []*Outline) {
+		for _, outline := range outlines {
+			if outline.XMLURL != "" {
+				feeds = append(feeds, &Feed{
+					Nickname:  outline.Text,
+					Title:     outline.Title,
+					Link:      outline.HTMLURL,
+					UpdateURL: outline.XMLURL,
+				})
+			}
+			walk(outline.Outlines)
+		}
+	}
+	walk(o.Outlines)
+
+	return feeds
+}
+
+type opmlDoc struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title        string `xml:"title,omitempty"`
+	DateCreated  string `xml:"dateCreated,omitempty"`
+	DateModified string `xml:"dateModified,omitempty"`
+	OwnerName    string `xml:"ownerName,omitempty"`
+	OwnerEmail   string `xml:"ownerEmail,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []*Outline `xml:"outline"`
+}
+
+// UnmarshalXML implements xml.Unmarshaler, so
+// that unknown attributes are kept. Known
+// attributes are matched ignoring case, as some
+// readers write xmlurl, for example.
+func (o *Outline) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			// Namespace declarations are written
+			// as needed by the encoder.
+			continue
+		}
+		if attr.Name.Space != "" {
+			o.Attrs = append(o.Attrs, attr)
+			continue
+		}
+		switch strings.ToLower(attr.Name.Local) {
+		case "text":
+			o.Text = attr.Value
+		case "title":
+			o.Title = attr.Value
+		case "type":
+			o.Type = attr.Value
+		case "xmlurl":
+			o.XMLURL = strings.TrimSpace(attr.Value)
+		case "htmlurl":
+			o.HTMLURL = strings.TrimSpace(attr.Value)
+		case "category":
+			for _, category := range strings.Split(attr.Value, ",") {
+				if category = strings.TrimSpace(category); category != "" {
+					o.Categories = append(o.Categories, category)
+				}
+			}
+		default:
+			o.Attrs = append(o.Attrs, attr)
+		}
+	}
+
+	var children struct {
+		Outlines []*Outline `xml:"outline"`
+	}
+	if err := d.DecodeElement(&children, &start); err != nil {
+		return err
+	}
+	o.Outlines = children.Outlines
+
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler.
+func (o *Outline) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = nil
+	add := func(name, value string) {
+		if value != "" {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+		}
+	}
+	// text is required, so fall back to the title.
+	text := o.Text
+	if text == "" {
+		text = o.Title
+	}
+	add("text", text)
+	add("title", o.Title)
+	add("type", o.Type)
+	add("xmlUrl", o.XMLURL)
+	add("htmlUrl", o.HTMLURL)
+	add("category", strings.Join(o.Categories, ","))
+	start.Attr = append(start.Attr, o.Attrs...)
+
+	return e.EncodeElement(struct {
+		Outlines []*Outline `xml:"outline"`
+	}{o.Outlines}, start)
+}
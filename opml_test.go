@@ -0,0 +1,115 @@
+package rss
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOPML(t *testing.T) {
+	file, err := os.Open("testdata/subscriptions.opml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	o, err := ParseOPML(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual("My subscriptions", o.Title, t)
+	assertEqual("Jane Doe", o.OwnerName, t)
+	if want := time.Date(2022, 3, 14, 9, 0, 0, 0, time.UTC); !o.DateCreated.Equal(want) {
+		t.Errorf("got date %v, want %v", o.DateCreated, want)
+	}
+
+	if len(o.Outlines) != 2 || len(o.Outlines[0].Outlines) != 2 {
+		t.Fatalf("got outlines %+v", o.Outlines)
+	}
+	news := o.Outlines[0].Outlines[0]
+	assertEqual("https://news.example.com/feed.xml", news.XMLURL, t)
+	assertEqual("https://news.example.com/", news.HTMLURL, t)
+	if !reflect.DeepEqual(news.Categories, []string{"/World", "/Politics"}) {
+		t.Errorf("got categories %q", news.Categories)
+	}
+	if len(news.Attrs) != 1 || news.Attrs[0].Name.Local != "id" || news.Attrs[0].Value != "42" {
+		t.Errorf("got other attributes %+v", news.Attrs)
+	}
+	town := o.Outlines[0].Outlines[1].Outlines[0]
+	assertEqual("https://town.example.org/rss", town.XMLURL, t)
+	if len(town.Attrs) != 1 || town.Attrs[0].Name.Local != "updateInterval" {
+		t.Errorf("got other attributes %+v", town.Attrs)
+	}
+
+	feeds := o.Feeds()
+	var urls []string
+	for _, f := range feeds {
+		urls = append(urls, f.UpdateURL)
+	}
+	want := []string{"https://news.example.com/feed.xml", "https://town.example.org/rss", "https://blog.example.com/atom.xml"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("got feeds %q, want %q", urls, want)
+	}
+	assertEqual("Example Blog", feeds[2].Nickname, t)
+	assertEqual("An Example Blog", feeds[2].Title, t)
+
+	// Writing the outline back out keeps its
+	// structure and attributes.
+	var buf bytes.Buffer
+	if err := o.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseOPML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Outlines[0].Outlines[1], o.Outlines[0].Outlines[1]) {
+		t.Errorf("got %+v after writing, want %+v", again.Outlines[0].Outlines[1], o.Outlines[0].Outlines[1])
+	}
+	if got := again.Outlines[0].Outlines[0]; got.Attrs[0].Value != "42" || !reflect.DeepEqual(got.Categories, news.Categories) {
+		t.Errorf("got %+v after writing", got)
+	}
+}
+
+func TestParseOPMLErrors(t *testing.T) {
+	if _, err := ParseOPML(strings.NewReader(`<rss version="2.0"></rss>`)); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got %v for a feed, want ErrUnknownFormat", err)
+	}
+
+	_, err := ParseOPML(strings.NewReader("<opml>\n<body>\n<outline text=\"x\">\n</body></opml>"))
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 4 {
+		t.Errorf("got %v, want a ParseError on line 4", err)
+	}
+}
+
+func TestWriteOPML(t *testing.T) {
+	feeds := []*Feed{
+		{Nickname: "Mine", Title: "A Feed", Link: "https://a.example.com/", UpdateURL: "https://a.example.com/feed"},
+		{Title: "B & Co", UpdateURL: "https://b.example.com/rss"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteOPML(&buf, feeds); err != nil {
+		t.Fatal(err)
+	}
+	o, err := ParseOPML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := o.Feeds()
+	if len(got) != 2 {
+		t.Fatalf("got %d feeds, want 2", len(got))
+	}
+	for i, f := range got {
+		if f.UpdateURL != feeds[i].UpdateURL || f.Title != feeds[i].Title || f.Link != feeds[i].Link {
+			t.Errorf("feed %d: got %+v", i, f)
+		}
+	}
+	assertEqual("Mine", got[0].Nickname, t)
+	assertEqual("B & Co", got[1].Nickname, t)
+}
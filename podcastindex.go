@@ -0,0 +1,461 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nsPodcastIndex is the namespace of the
+// Podcasting 2.0 elements maintained by
+// podcastindex.org.
+const nsPodcastIndex = "https://podcastindex.org/namespace/1.0"
+
+// PodcastIndex holds the Podcasting 2.0 metadata
+// of a feed or an episode, from the podcast
+// namespace. Some fields only apply to one or
+// the other.
+type PodcastIndex struct {
+	// These apply to a feed or an episode.
+	Persons []*PodcastPerson `json:"persons,omitempty"`
+	Values  []*PodcastValue  `json:"values,omitempty"`
+
+	// These apply to a feed.
+	Locked  *PodcastLocked    `json:"locked,omitempty"`
+	GUID    string            `json:"guid,omitempty"` // Permanent ID of the podcast, which survives moves.
+	Funding []*PodcastFunding `json:"funding,omitempty"`
+
+	// These apply to an episode.
+	Transcripts         []*PodcastTranscript         `json:"transcripts,omitempty"`
+	Chapters            *PodcastChapters             `json:"chapters,omitempty"`
+	Soundbites          []*PodcastSoundbite          `json:"soundbites,omitempty"`
+	AlternateEnclosures []*PodcastAlternateEnclosure `json:"alternateenclosures,omitempty"`
+}
+
+// PodcastPerson is someone who takes part in a
+// podcast or an episode.
+type PodcastPerson struct {
+	Name  string `json:"name"`
+	Role  string `json:"role"`            // Such as "host" or "guest". Defaults to "host".
+	Group string `json:"group"`           // Such as "cast" or "writing". Defaults to "cast".
+	Image string `json:"image,omitempty"` // URL of a picture of the person.
+	Href  string `json:"href,omitempty"`  // URL of a page about the person.
+}
+
+// PodcastValue says how listeners can send
+// payments, such as over the Lightning network,
+// to a podcast or an episode.
+type PodcastValue struct {
+	Type       string                   `json:"type"`                // Such as "lightning".
+	Method     string                   `json:"method"`              // Such as "keysend".
+	Suggested  string                   `json:"suggested,omitempty"` // Suggested amount per minute, as given.
+	Recipients []*PodcastValueRecipient `json:"recipients,omitempty"`
+}
+
+// PodcastValueRecipient is one of the recipients
+// of a payment, who receives Split shares of it.
+type PodcastValueRecipient struct {
+	Name        string `json:"name,omitempty"`
+	Type        string `json:"type"` // Such as "node".
+	Address     string `json:"address"`
+	Split       int    `json:"split"`
+	CustomKey   string `json:"customkey,omitempty"`
+	CustomValue string `json:"customvalue,omitempty"`
+	Fee         bool   `json:"fee,omitempty"` // Whether the split is a fee taken before the others.
+}
+
+// PodcastLocked says whether a podcast may be
+// imported by other hosting platforms.
+type PodcastLocked struct {
+	Locked bool   `json:"locked"`
+	Owner  string `json:"owner,omitempty"` // Email address of the owner, who can unlock it.
+}
+
+// PodcastFunding is a link to a page where
+// listeners can support a podcast.
+type PodcastFunding struct {
+	URL  string `json:"url"`
+	Text string `json:"text,omitempty"`
+}
+
+// PodcastTranscript is a transcript or closed
+// captions for an episode.
+type PodcastTranscript struct {
+	URL      string `json:"url"`
+	Type     string `json:"type"` // Such as "text/vtt" or "application/x-subrip".
+	Language string `json:"language,omitempty"`
+	Rel      string `json:"rel,omitempty"` // "captions" if the file is closed captions.
+}
+
+// Get uses DefaultRequestFunc to fetch a
+// transcript.
+func (t *PodcastTranscript) Get() (io.ReadCloser, error) {
+	return t.GetContext(context.Background())
+}
+
+// GetContext uses DefaultRequestFunc to fetch a
+// transcript. The request is cancelled if ctx is
+// done before the body has been read.
+func (t *PodcastTranscript) GetContext(ctx context.Context) (io.ReadCloser, error) {
+	if t == nil || t.URL == "" {
+		return nil, ErrNoTranscript
+	}
+
+	return get(ctx, t.URL)
+}
+
+// PodcastChapters links to the chapters of an
+// episode.
+type PodcastChapters struct {
+	URL  string `json:"url"`
+	Type string `json:"type"` // Usually "application/json+chapters".
+}
+
+// Get uses DefaultRequestFunc to fetch the
+// chapters file.
+func (c *PodcastChapters) Get() (io.ReadCloser, error) {
+	return c.GetContext(context.Background())
+}
+
+// GetContext uses DefaultRequestFunc to fetch the
+// chapters file. The request is cancelled if ctx
+// is done before the body has been read.
+func (c *PodcastChapters) GetContext(ctx context.Context) (io.ReadCloser, error) {
+	if c == nil || c.URL == "" {
+		return nil, ErrNoChapters
+	}
+
+	return get(ctx, c.URL)
+}
+
+// Chapters uses DefaultRequestFunc to fetch the
+// chapters file and parses it with ParseChapters.
+// Only the JSON chapters format is supported.
+func (c *PodcastChapters) Chapters(ctx context.Context) ([]*Chapter, error) {
+	if c != nil && c.Type != "" {
+		mediaType, _, _ := mime.ParseMediaType(c.Type)
+		if !strings.Contains(strings.ToLower(mediaType), "json") {
+			return nil, fmt.Errorf("%w: chapters of type %q", ErrUnknownFormat, c.Type)
+		}
+	}
+
+	body, err := c.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ParseChapters(body)
+}
+
+// Chapter is a chapter of an episode.
+type Chapter struct {
+	Start  time.Duration `json:"start"`
+	End    time.Duration `json:"end,omitempty"` // Zero if not given.
+	Title  string        `json:"title,omitempty"`
+	Image  string        `json:"image,omitempty"`
+	URL    string        `json:"url,omitempty"`
+	Hidden bool          `json:"hidden,omitempty"` // Whether the chapter is left out of the table of contents.
+}
+
+// ParseChapters reads a chapters file in the
+// Podcasting 2.0 JSON chapters format from r.
+func ParseChapters(r io.Reader) ([]*Chapter, error) {
+	var doc struct {
+		Chapters []struct {
+			StartTime float64 `json:"startTime"`
+			EndTime   float64 `json:"endTime"`
+			Title     string  `json:"title"`
+			Img       string  `json:"img"`
+			URL       string  `json:"url"`
+			TOC       *bool   `json:"toc"`
+		} `json:"chapters"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing chapters: %w", err)
+	}
+
+	chapters := make([]*Chapter, 0, len(doc.Chapters))
+	for _, c := range doc.Chapters {
+		chapters = append(chapters, &Chapter{
+			Start:  seconds(c.StartTime),
+			End:    seconds(c.EndTime),
+			Title:  c.Title,
+			Image:  c.Img,
+			URL:    c.URL,
+			Hidden: c.TOC != nil && !*c.TOC,
+		})
+	}
+
+	return chapters, nil
+}
+
+// PodcastSoundbite is a short part of an episode
+// that can be used to promote it.
+type PodcastSoundbite struct {
+	Start    time.Duration `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Title    string        `json:"title,omitempty"`
+}
+
+// PodcastAlternateEnclosure is another version
+// of an episode's media, such as a lower bitrate
+// or video, which may be available from several
+// sources.
+type PodcastAlternateEnclosure struct {
+	Type    string           `json:"type"`
+	Length  uint             `json:"length,omitempty"`
+	Bitrate float64          `json:"bitrate,omitempty"` // In bits per second.
+	Height  int              `json:"height,omitempty"`  // In pixels, for video.
+	Lang    string           `json:"lang,omitempty"`
+	Title   string           `json:"title,omitempty"`
+	Rel     string           `json:"rel,omitempty"`
+	Codecs  string           `json:"codecs,omitempty"`
+	Default bool             `json:"default,omitempty"` // Whether this is the same as the item's enclosure.
+	Sources []*PodcastSource `json:"sources,omitempty"`
+}
+
+// PodcastSource is one of the places that an
+// alternate enclosure can be fetched from.
+type PodcastSource struct {
+	URI         string `json:"uri"`
+	ContentType string `json:"contenttype,omitempty"` // Only given if it differs from the enclosure's Type.
+}
+
+// Enclosures returns an Enclosure for each of
+// the sources, in order of preference, so that
+// they can be fetched in the same way as an
+// item's enclosures.
+func (a *PodcastAlternateEnclosure) Enclosures() []*Enclosure {
+	out := make([]*Enclosure, 0, len(a.Sources))
+	for _, source := range a.Sources {
+		next := &Enclosure{URL: source.URI, Type: a.Type, Length: a.Length}
+		if source.ContentType != "" {
+			next.Type = source.ContentType
+		}
+		out = append(out, next)
+	}
+	return out
+}
+
+// podcastIndexElements holds the elements in the
+// Podcasting 2.0 namespace of a channel or an
+// item.
+type podcastIndexElements struct {
+	Persons             []podcastPerson             `xml:"person"`
+	Values              []podcastValue              `xml:"value"`
+	Locked              *podcastLocked              `xml:"locked"`
+	GUID                string                      `xml:"guid"`
+	Funding             []podcastFunding            `xml:"funding"`
+	Transcripts         []podcastTranscript         `xml:"transcript"`
+	Chapters            *podcastChapters            `xml:"chapters"`
+	Soundbites          []podcastSoundbite          `xml:"soundbite"`
+	AlternateEnclosures []podcastAlternateEnclosure `xml:"alternateEnclosure"`
+}
+
+type podcastPerson struct {
+	Role  string `xml:"role,attr"`
+	Group string `xml:"group,attr"`
+	Img   string `xml:"img,attr"`
+	Href  string `xml:"href,attr"`
+	Name  string `xml:",chardata"`
+}
+
+type podcastValue struct {
+	Type       string                  `xml:"type,attr"`
+	Method     string                  `xml:"method,attr"`
+	Suggested  string                  `xml:"suggested,attr"`
+	Recipients []podcastValueRecipient `xml:"valueRecipient"`
+}
+
+type podcastValueRecipient struct {
+	Name        string `xml:"name,attr"`
+	Type        string `xml:"type,attr"`
+	Address     string `xml:"address,attr"`
+	Split       string `xml:"split,attr"`
+	CustomKey   string `xml:"customKey,attr"`
+	CustomValue string `xml:"customValue,attr"`
+	Fee         string `xml:"fee,attr"`
+}
+
+type podcastLocked struct {
+	Owner  string `xml:"owner,attr"`
+	Locked string `xml:",chardata"`
+}
+
+type podcastFunding struct {
+	URL  string `xml:"url,attr"`
+	Text string `xml:",chardata"`
+}
+
+type podcastTranscript struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Language string `xml:"language,attr"`
+	Rel      string `xml:"rel,attr"`
+}
+
+type podcastChapters struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type podcastSoundbite struct {
+	StartTime string `xml:"startTime,attr"`
+	Duration  string `xml:"duration,attr"`
+	Title     string `xml:",chardata"`
+}
+
+type podcastAlternateEnclosure struct {
+	Type    string          `xml:"type,attr"`
+	Length  string          `xml:"length,attr"`
+	Bitrate string          `xml:"bitrate,attr"`
+	Height  string          `xml:"height,attr"`
+	Lang    string          `xml:"lang,attr"`
+	Title   string          `xml:"title,attr"`
+	Rel     string          `xml:"rel,attr"`
+	Codecs  string          `xml:"codecs,attr"`
+	Default string          `xml:"default,attr"`
+	Sources []podcastSource `xml:"source"`
+}
+
+type podcastSource struct {
+	URI         string `xml:"uri,attr"`
+	ContentType string `xml:"contentType,attr"`
+}
+
+// register adds p to ext for the Podcasting 2.0
+// namespace, including feeds that use the podcast
+// prefix without declaring it.
+func (p *podcastIndexElements) register(ext map[string]interface{}) {
+	ext[nsPodcastIndex] = p
+	ext["podcast"] = p
+}
+
+// PodcastIndex returns the Podcasting 2.0
+// metadata, or nil if there was none.
+func (p *podcastIndexElements) PodcastIndex() *PodcastIndex {
+	out := &PodcastIndex{GUID: strings.TrimSpace(p.GUID)}
+	for _, person := range p.Persons {
+		next := &PodcastPerson{
+			Name:  strings.TrimSpace(person.Name),
+			Role:  strings.ToLower(strings.TrimSpace(person.Role)),
+			Group: strings.ToLower(strings.TrimSpace(person.Group)),
+			Image: strings.TrimSpace(person.Img),
+			Href:  strings.TrimSpace(person.Href),
+		}
+		if next.Role == "" {
+			next.Role = "host"
+		}
+		if next.Group == "" {
+			next.Group = "cast"
+		}
+		if next.Name != "" {
+			out.Persons = append(out.Persons, next)
+		}
+	}
+	for _, value := range p.Values {
+		next := &PodcastValue{
+			Type:      strings.TrimSpace(value.Type),
+			Method:    strings.TrimSpace(value.Method),
+			Suggested: strings.TrimSpace(value.Suggested),
+		}
+		for _, r := range value.Recipients {
+			recipient := &PodcastValueRecipient{
+				Name:        strings.TrimSpace(r.Name),
+				Type:        strings.TrimSpace(r.Type),
+				Address:     strings.TrimSpace(r.Address),
+				CustomKey:   strings.TrimSpace(r.CustomKey),
+				CustomValue: strings.TrimSpace(r.CustomValue),
+				Fee:         parseITunesBool(r.Fee, ""),
+			}
+			recipient.Split, _ = strconv.Atoi(strings.TrimSpace(r.Split))
+			if recipient.Address != "" {
+				next.Recipients = append(next.Recipients, recipient)
+			}
+		}
+		if next.Type != "" {
+			out.Values = append(out.Values, next)
+		}
+	}
+	if p.Locked != nil {
+		out.Locked = &PodcastLocked{
+			Locked: parseITunesBool(p.Locked.Locked, ""),
+			Owner:  strings.TrimSpace(p.Locked.Owner),
+		}
+	}
+	for _, funding := range p.Funding {
+		if url := strings.TrimSpace(funding.URL); url != "" {
+			out.Funding = append(out.Funding, &PodcastFunding{URL: url, Text: strings.TrimSpace(funding.Text)})
+		}
+	}
+	for _, t := range p.Transcripts {
+		if url := strings.TrimSpace(t.URL); url != "" {
+			out.Transcripts = append(out.Transcripts, &PodcastTranscript{
+				URL:      url,
+				Type:     strings.TrimSpace(t.Type),
+				Language: strings.TrimSpace(t.Language),
+				Rel:      strings.TrimSpace(t.Rel),
+			})
+		}
+	}
+	if p.Chapters != nil && strings.TrimSpace(p.Chapters.URL) != "" {
+		out.Chapters = &PodcastChapters{
+			URL:  strings.TrimSpace(p.Chapters.URL),
+			Type: strings.TrimSpace(p.Chapters.Type),
+		}
+	}
+	for _, s := range p.Soundbites {
+		next := &PodcastSoundbite{
+			Start:    parseITunesDuration(s.StartTime),
+			Duration: parseITunesDuration(s.Duration),
+			Title:    strings.TrimSpace(s.Title),
+		}
+		if next.Duration != 0 {
+			out.Soundbites = append(out.Soundbites, next)
+		}
+	}
+	for _, alt := range p.AlternateEnclosures {
+		next := &PodcastAlternateEnclosure{
+			Type:    strings.TrimSpace(alt.Type),
+			Lang:    strings.TrimSpace(alt.Lang),
+			Title:   strings.TrimSpace(alt.Title),
+			Rel:     strings.TrimSpace(alt.Rel),
+			Codecs:  strings.TrimSpace(alt.Codecs),
+			Default: parseITunesBool(alt.Default, ""),
+		}
+		if n, err := strconv.ParseUint(strings.TrimSpace(alt.Length), 10, 0); err == nil {
+			next.Length = uint(n)
+		}
+		next.Bitrate, _ = strconv.ParseFloat(strings.TrimSpace(alt.Bitrate), 64)
+		next.Height, _ = strconv.Atoi(strings.TrimSpace(alt.Height))
+		for _, source := range alt.Sources {
+			if uri := strings.TrimSpace(source.URI); uri != "" {
+				next.Sources = append(next.Sources, &PodcastSource{URI: uri, ContentType: strings.TrimSpace(source.ContentType)})
+			}
+		}
+		if len(next.Sources) > 0 {
+			out.AlternateEnclosures = append(out.AlternateEnclosures, next)
+		}
+	}
+
+	if reflect.DeepEqual(out, &PodcastIndex{}) {
+		return nil
+	}
+	return out
+}
+
+// seconds converts a number of seconds, which
+// may be fractional, to a time.Duration.
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
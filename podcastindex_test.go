@@ -0,0 +1,199 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePodcastIndex(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/rss_2.0_podcastindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &PodcastIndex{
+		Persons: []*PodcastPerson{
+			{Name: "John Smith", Role: "host", Group: "cast", Image: "http://example.com/images/johnsmith.jpg", Href: "https://example.com/johnsmith/blog"},
+			{Name: "Jane Doe", Role: "guest", Group: "cast", Href: "https://www.imdb.com/name/nm0427852888/"},
+		},
+		Values: []*PodcastValue{{
+			Type:      "lightning",
+			Method:    "keysend",
+			Suggested: "0.00000015000",
+			Recipients: []*PodcastValueRecipient{
+				{Name: "Alice (Podcaster)", Type: "node", Address: "02d5c1bf8b940dc9cadca86d1b0a3c37fbe39cee4c7e839e33bef9174531d27f52", Split: 90},
+				{Name: "Hosting Provider", Type: "node", Address: "03ae9f91a0cb8ff43840e3c322c4c61f019d8c1c3cea15a25cfc425ac605e61a4a", Split: 10, CustomKey: "696969", CustomValue: "1234", Fee: true},
+			},
+		}},
+		Locked: &PodcastLocked{Locked: true, Owner: "podcastowner@example.com"},
+		GUID:   "917393e3-1b1e-5cef-ace4-edaa54e1f810",
+		Funding: []*PodcastFunding{
+			{URL: "https://example.com/donate", Text: "Support the show!"},
+			{URL: "https://example.com/members"},
+		},
+	}
+	if !reflect.DeepEqual(feed.PodcastIndex, want) {
+		t.Errorf("got podcast %+v, want %+v", feed.PodcastIndex, want)
+	}
+
+	if len(feed.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Items))
+	}
+	item := feed.Items[0]
+	assertEqual("https://example.com/podcast/ep0003", item.ID, t)
+	want = &PodcastIndex{
+		Persons: []*PodcastPerson{
+			{Name: "Alice", Role: "guest", Group: "writing", Image: "http://example.com/images/alice.jpg"},
+		},
+		Transcripts: []*PodcastTranscript{
+			{URL: "https://example.com/ep3/transcript.vtt", Type: "text/vtt", Language: "en", Rel: "captions"},
+			{URL: "https://example.com/ep3/transcript.srt", Type: "application/x-subrip"},
+		},
+		Chapters: &PodcastChapters{URL: "https://example.com/ep3_chapters.json", Type: "application/json+chapters"},
+		Soundbites: []*PodcastSoundbite{
+			{Start: 33833 * time.Millisecond, Duration: time.Minute, Title: "Why the Podcast Namespace Matters"},
+			{Start: 1234500 * time.Millisecond, Duration: 42250 * time.Millisecond},
+		},
+		AlternateEnclosures: []*PodcastAlternateEnclosure{
+			{
+				Type:    "audio/opus",
+				Length:  32400000,
+				Bitrate: 96000,
+				Title:   "High quality",
+				Codecs:  "opus",
+				Sources: []*PodcastSource{
+					{URI: "https://example.com/file-03.opus"},
+					{URI: "ipfs://QmdwGqd3d2gFPGeJNLLCshdiPert45fMu84552Y4XHTy4y", ContentType: "audio/ogg"},
+				},
+			},
+			{
+				Type:    "video/mp4",
+				Height:  1080,
+				Lang:    "en-US",
+				Sources: []*PodcastSource{{URI: "https://example.com/file-03.mp4"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(item.PodcastIndex, want) {
+		t.Errorf("got episode %+v, want %+v", item.PodcastIndex, want)
+	}
+	if feed.Items[1].PodcastIndex != nil {
+		t.Errorf("got episode %+v, want nil", feed.Items[1].PodcastIndex)
+	}
+
+	enclosures := item.PodcastIndex.AlternateEnclosures[0].Enclosures()
+	wantEnclosures := []*Enclosure{
+		{URL: "https://example.com/file-03.opus", Type: "audio/opus", Length: 32400000},
+		{URL: "ipfs://QmdwGqd3d2gFPGeJNLLCshdiPert45fMu84552Y4XHTy4y", Type: "audio/ogg", Length: 32400000},
+	}
+	if !reflect.DeepEqual(enclosures, wantEnclosures) {
+		t.Errorf("got enclosures %v, want %v", enclosures, wantEnclosures)
+	}
+}
+
+func TestParsePodcastIndexAtom(t *testing.T) {
+	feed := mustParse(t, `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:podcast="https://podcastindex.org/namespace/1.0">
+		<title>Show</title>
+		<podcast:funding url="https://example.com/donate">Donate</podcast:funding>
+		<entry><id>1</id><title>Episode</title>
+			<podcast:chapters url="https://example.com/chapters.json" type="application/json+chapters"/>
+		</entry>
+	</feed>`)
+
+	if p := feed.PodcastIndex; p == nil || len(p.Funding) != 1 || p.Funding[0].Text != "Donate" {
+		t.Errorf("got podcast %+v", p)
+	}
+	if p := feed.Items[0].PodcastIndex; p == nil || p.Chapters == nil || p.Chapters.URL != "https://example.com/chapters.json" {
+		t.Errorf("got episode %+v", p)
+	}
+}
+
+func TestParsePodcastIndexUndeclared(t *testing.T) {
+	feed := mustParse(t, `<rss version="2.0"><channel><title>Show</title>
+		<podcast:guid>917393e3-1b1e-5cef-ace4-edaa54e1f810</podcast:guid>
+		<item><guid>1</guid><podcast:guid>2</podcast:guid><podcast:locked>no</podcast:locked></item>
+	</channel></rss>`)
+
+	if p := feed.PodcastIndex; p == nil || p.GUID != "917393e3-1b1e-5cef-ace4-edaa54e1f810" {
+		t.Errorf("got podcast %+v", p)
+	}
+	item := feed.Items[0]
+	assertEqual("1", item.ID, t)
+	if p := item.PodcastIndex; p == nil || p.Locked == nil || p.Locked.Locked {
+		t.Errorf("got episode %+v", p)
+	}
+	if feed := mustParse(t, `<rss version="2.0"><channel><item><guid>1</guid></item></channel></rss>`); feed.PodcastIndex != nil || feed.Items[0].PodcastIndex != nil {
+		t.Errorf("got podcast metadata for a plain feed")
+	}
+}
+
+func TestPodcastChapters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/chapters.json":
+			w.Write([]byte(`{"version": "1.2.0", "chapters": [
+				{"startTime": 0, "title": "Intro", "toc": false},
+				{"startTime": 168.5, "endTime": 300, "title": "Hiking Boots", "img": "https://example.com/boots.jpg", "url": "https://example.com/boots"}
+			]}`))
+		case "/transcript.vtt":
+			w.Write([]byte("WEBVTT\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := &PodcastChapters{URL: server.URL + "/chapters.json", Type: "application/json+chapters"}
+	chapters, err := c.Chapters(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Chapter{
+		{Title: "Intro", Hidden: true},
+		{Start: 168500 * time.Millisecond, End: 5 * time.Minute, Title: "Hiking Boots", Image: "https://example.com/boots.jpg", URL: "https://example.com/boots"},
+	}
+	if !reflect.DeepEqual(chapters, want) {
+		t.Errorf("got chapters %+v, want %+v", chapters, want)
+	}
+
+	c.Type = "application/xml+chapters"
+	if _, err := c.Chapters(context.Background()); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got error %v for XML chapters, want ErrUnknownFormat", err)
+	}
+	c = &PodcastChapters{URL: server.URL + "/missing.json", Type: "application/json+chapters"}
+	var statusErr *HTTPStatusError
+	if _, err := c.Chapters(context.Background()); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got error %v for missing chapters file, want a 404 *HTTPStatusError", err)
+	}
+	if _, err := (&PodcastTranscript{URL: server.URL + "/missing.vtt"}).Get(); !errors.As(err, &statusErr) {
+		t.Errorf("got error %v for missing transcript file, want an *HTTPStatusError", err)
+	}
+	if _, err := (*PodcastChapters)(nil).Get(); err != ErrNoChapters {
+		t.Errorf("got error %v for missing chapters, want ErrNoChapters", err)
+	}
+
+	transcript := &PodcastTranscript{URL: server.URL + "/transcript.vtt", Type: "text/vtt"}
+	body, err := transcript.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, err := ioutil.ReadAll(body)
+	if err != nil || !strings.HasPrefix(string(data), "WEBVTT") {
+		t.Errorf("got transcript %q, %v", data, err)
+	}
+	if _, err := (&PodcastTranscript{}).Get(); err != ErrNoTranscript {
+		t.Errorf("got error %v for a missing transcript, want ErrNoTranscript", err)
+	}
+}
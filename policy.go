@@ -0,0 +1,173 @@
+package rss
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// A RefreshPolicy decides when a feed should next be
+// checked for new items.
+type RefreshPolicy interface {
+	// NextRefresh returns the time f should next be
+	// checked, after an update attempt made at now
+	// that returned err. err is nil if the update
+	// succeeded, including if the feed had not been
+	// modified.
+	//
+	// hint is the earliest time that the feed itself
+	// or its server asked to be checked again, through
+	// ttl, the Syndication module, or HTTP headers such
+	// as Cache-Control and Retry-After. It is the zero
+	// time if they did not say.
+	//
+	// NextRefresh is called after f has been updated,
+	// and f.Failures counts the failed attempts in a
+	// row, including this one. f is locked, so its
+	// fields can be read but its methods must not be
+	// called.
+	NextRefresh(f *Feed, now, hint time.Time, err error) time.Time
+}
+
+// DefaultRefreshPolicy is used by Feed.Update for
+// feeds that have no RefreshPolicy. The default
+// value checks a feed when it asks to be checked,
+// or after DefaultRefreshInterval if it does not
+// say. After a failed update, the previous Refresh
+// time is kept unless the server asked for a later
+// one.
+var DefaultRefreshPolicy RefreshPolicy = defaultRefreshPolicy{}
+
+type defaultRefreshPolicy struct{}
+
+func (defaultRefreshPolicy) NextRefresh(f *Feed, now, hint time.Time, err error) time.Time {
+	if err != nil {
+		if hint.After(f.Refresh) {
+			return hint
+		}
+		return f.Refresh
+	}
+	if !hint.IsZero() {
+		return hint
+	}
+	return nextRefresh(now, DefaultRefreshInterval, f.SkipHours, f.SkipDays)
+}
+
+// AdaptiveRefreshPolicy is a RefreshPolicy that checks
+// each feed about as often as it publishes new items,
+// judging by the dates of the items it has seen. A
+// feed that posts every few minutes is checked every
+// few minutes, and one that posts twice a year is
+// checked rarely.
+//
+// After a failed update, the interval is doubled for
+// each failure in a row, up to MaxInterval. A feed is
+// never checked sooner than it or its server asks.
+type AdaptiveRefreshPolicy struct {
+	// MinInterval and MaxInterval bound the time
+	// between checks. If zero, they default to 15
+	// minutes and 7 days.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+
+	// Jitter is the fraction by which each interval
+	// is randomly lengthened or shortened, so that
+	// feeds added together are not all checked
+	// together. For example, 0.1 allows ±10%. The
+	// result is still bounded by MinInterval and
+	// MaxInterval.
+	Jitter float64
+
+	// History is the number of the most recent items
+	// used to estimate how often a feed publishes. If
+	// zero, it defaults to 20.
+	History int
+}
+
+// Interval returns the estimated time between new
+// items in f, as of now, within the policy's bounds.
+// If f has fewer than two dated items, the feed's own
+// hint is used, or DefaultRefreshInterval.
+func (p *AdaptiveRefreshPolicy) Interval(f *Feed, now, hint time.Time) time.Duration {
+	history := p.History
+	if history <= 0 {
+		history = 20
+	}
+
+	var dates []time.Time
+	for _, item := range f.Items {
+		// Ignore items from the future, which are
+		// most likely down to a bad clock.
+		if item.DateValid && !item.Date.After(now) {
+			dates = append(dates, item.Date)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].After(dates[j]) })
+	if len(dates) > history {
+		dates = dates[:history]
+	}
+
+	var interval time.Duration
+	switch {
+	case len(dates) >= 2:
+		// Count the time since the newest item as a
+		// gap too, so that a feed that has gone quiet
+		// is checked less often.
+		oldest := dates[len(dates)-1]
+		interval = now.Sub(oldest) / time.Duration(len(dates))
+	case hint.After(now):
+		interval = hint.Sub(now)
+	default:
+		interval = DefaultRefreshInterval
+	}
+
+	return p.clamp(interval)
+}
+
+// NextRefresh implements RefreshPolicy.
+func (p *AdaptiveRefreshPolicy) NextRefresh(f *Feed, now, hint time.Time, err error) time.Time {
+	interval := p.Interval(f, now, hint)
+	if err != nil {
+		for i := uint32(0); i < f.Failures && interval < p.max(); i++ {
+			interval *= 2
+		}
+	}
+
+	// Clamp after adding jitter, so that it cannot
+	// take the interval out of bounds.
+	if p.Jitter > 0 {
+		interval += time.Duration((2*rand.Float64() - 1) * p.Jitter * float64(interval))
+	}
+	interval = p.clamp(interval)
+
+	next := now.Add(interval)
+	if hint.After(next) {
+		next = hint
+	}
+
+	return nextRefresh(next, 0, f.SkipHours, f.SkipDays)
+}
+
+func (p *AdaptiveRefreshPolicy) min() time.Duration {
+	if p.MinInterval > 0 {
+		return p.MinInterval
+	}
+	return 15 * time.Minute
+}
+
+func (p *AdaptiveRefreshPolicy) max() time.Duration {
+	if p.MaxInterval > 0 {
+		return p.MaxInterval
+	}
+	return 7 * 24 * time.Hour
+}
+
+func (p *AdaptiveRefreshPolicy) clamp(d time.Duration) time.Duration {
+	if min := p.min(); d < min {
+		return min
+	}
+	if max := p.max(); d > max {
+		return max
+	}
+	return d
+}
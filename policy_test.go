@@ -0,0 +1,178 @@
+package rss
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// feedWithItemsEvery returns a feed with n items,
+// published every interval until now.
+func feedWithItemsEvery(n int, interval time.Duration, now time.Time) *Feed {
+	f := new(Feed)
+	for i := 0; i < n; i++ {
+		f.Items = append(f.Items, &Item{
+			Date:      now.Add(-time.Duration(i+1) * interval),
+			DateValid: true,
+		})
+	}
+	return f
+}
+
+func TestAdaptiveRefreshInterval(t *testing.T) {
+	now := time.Date(2022, 3, 14, 12, 0, 0, 0, time.UTC)
+	p := &AdaptiveRefreshPolicy{}
+
+	tests := []struct {
+		name string
+		feed *Feed
+		hint time.Time
+		want time.Duration
+	}{
+		{"busy", feedWithItemsEvery(10, 30*time.Minute, now), time.Time{}, 30 * time.Minute},
+		{"too busy", feedWithItemsEvery(10, time.Minute, now), time.Time{}, 15 * time.Minute},
+		{"rare", feedWithItemsEvery(4, 180*24*time.Hour, now), time.Time{}, 7 * 24 * time.Hour},
+		{"history", feedWithItemsEvery(100, time.Hour, now), time.Time{}, time.Hour},
+		{"no history", new(Feed), time.Time{}, DefaultRefreshInterval},
+		{"no history with hint", new(Feed), now.Add(3 * time.Hour), 3 * time.Hour},
+	}
+
+	for _, test := range tests {
+		if got := p.Interval(test.feed, now, test.hint); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// Items from the future are ignored, and a feed
+	// that has gone quiet is checked less often.
+	quiet := feedWithItemsEvery(4, time.Hour, now.Add(-20*time.Hour))
+	quiet.Items = append(quiet.Items, &Item{Date: now.Add(time.Hour), DateValid: true})
+	if got, want := p.Interval(quiet, now, time.Time{}), 6*time.Hour; got != want {
+		t.Errorf("quiet: got %v, want %v", got, want)
+	}
+}
+
+func TestAdaptiveRefreshBackoff(t *testing.T) {
+	now := time.Date(2022, 3, 14, 12, 0, 0, 0, time.UTC)
+	p := &AdaptiveRefreshPolicy{MaxInterval: 24 * time.Hour}
+	f := feedWithItemsEvery(10, time.Hour, now)
+	fail := errors.New("failed")
+
+	for failures, want := range []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour, 8 * time.Hour, 16 * time.Hour, 24 * time.Hour, 24 * time.Hour} {
+		f.Failures = uint32(failures)
+		err := fail
+		if failures == 0 {
+			err = nil
+		}
+		if got := p.NextRefresh(f, now, time.Time{}, err); !got.Equal(now.Add(want)) {
+			t.Errorf("%d failures: got %v, want %v", failures, got.Sub(now), want)
+		}
+	}
+
+	// The feed's own hint still wins.
+	hint := now.Add(48 * time.Hour)
+	if got := p.NextRefresh(f, now, hint, nil); !got.Equal(hint) {
+		t.Errorf("got %v, want the hint %v", got, hint)
+	}
+}
+
+func TestAdaptiveRefreshJitter(t *testing.T) {
+	now := time.Date(2022, 3, 14, 12, 0, 0, 0, time.UTC)
+	p := &AdaptiveRefreshPolicy{Jitter: 0.1}
+	f := feedWithItemsEvery(10, 100*time.Minute, now)
+
+	seen := make(map[time.Time]bool)
+	for i := 0; i < 100; i++ {
+		got := p.NextRefresh(f, now, time.Time{}, nil)
+		if d := got.Sub(now); d < 90*time.Minute || d > 110*time.Minute {
+			t.Fatalf("got interval %v, want 100m±10%%", d)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("jitter did not vary the refresh time")
+	}
+
+	// Jitter never takes the interval out of bounds.
+	bounded := &AdaptiveRefreshPolicy{MinInterval: time.Hour, MaxInterval: 2 * time.Hour, Jitter: 0.5}
+	for _, gap := range []time.Duration{time.Minute, 24 * time.Hour} {
+		f := feedWithItemsEvery(10, gap, now)
+		for i := 0; i < 100; i++ {
+			got := bounded.NextRefresh(f, now, time.Time{}, nil)
+			if d := got.Sub(now); d < time.Hour || d > 2*time.Hour {
+				t.Fatalf("got interval %v for items every %v, want between 1h and 2h", d, gap)
+			}
+		}
+	}
+}
+
+type recordingPolicy struct {
+	errs  []error
+	hints []time.Time
+}
+
+func (p *recordingPolicy) NextRefresh(f *Feed, now, hint time.Time, err error) time.Time {
+	p.errs = append(p.errs, err)
+	p.hints = append(p.hints, hint)
+	return time.Time{}
+}
+
+func TestUpdateRefreshPolicy(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		data, _ := ioutil.ReadFile("testdata/atom_1.0")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	policy := new(recordingPolicy)
+	feed := &Feed{UpdateURL: server.URL, RequestFunc: server.Client().Do, RefreshPolicy: policy}
+
+	for _, code := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK} {
+		status = code
+		err := feed.Update()
+		if (code == http.StatusOK) != (err == nil) {
+			t.Errorf("%d: got error %v", code, err)
+		}
+		if code != http.StatusOK && feed.Failures == 0 {
+			t.Errorf("%d: failure was not counted", code)
+		}
+	}
+
+	if feed.Failures != 0 {
+		t.Errorf("got %d failures after success, want 0", feed.Failures)
+	}
+	if len(policy.errs) != 3 || policy.errs[0] == nil || policy.errs[2] != nil {
+		t.Errorf("policy got errors %v", policy.errs)
+	}
+	// Atom feeds give no hint of their own.
+	if !policy.hints[2].IsZero() {
+		t.Errorf("got hint %v for Atom feed, want none", policy.hints[2])
+	}
+}
+
+func TestDefaultRefreshPolicyFailure(t *testing.T) {
+	refresh := time.Now().Add(-time.Minute)
+	feed := &Feed{UpdateURL: "http://example.com/feed", Refresh: refresh}
+	fail := errors.New("failed")
+
+	err := feed.UpdateByFunc(func(url string) (*http.Response, error) {
+		return nil, fail
+	})
+	if err != fail {
+		t.Fatalf("got %v, want %v", err, fail)
+	}
+	if !feed.Refresh.Equal(refresh) {
+		t.Errorf("failed update changed refresh from %v to %v", refresh, feed.Refresh)
+	}
+	if feed.Failures != 1 {
+		t.Errorf("got %d failures, want 1", feed.Failures)
+	}
+}
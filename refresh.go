@@ -0,0 +1,236 @@
+package rss
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setDefaultRefresh sets f.Refresh to
+// DefaultRefreshInterval after now, if the
+// feed has not given a refresh time.
+func (f *Feed) setDefaultRefresh(now time.Time) {
+	if f.Refresh.IsZero() {
+		f.Refresh = nextRefresh(now, DefaultRefreshInterval, f.SkipHours, f.SkipDays)
+	}
+}
+
+// nextRefresh returns the first time, at least
+// ttl after now, that is not in one of the hours
+// or days to skip. As in the RSS specification,
+// skipHours and skipDays are in UTC (GMT).
+//
+// If every hour is skipped, the hints are ignored
+// and now+ttl is returned.
+func nextRefresh(now time.Time, ttl time.Duration, skipHours []int, skipDays []time.Weekday) time.Time {
+	next := now.Add(ttl)
+	if len(skipHours) == 0 && len(skipDays) == 0 {
+		return next
+	}
+
+	var hours [24]bool
+	for _, hour := range skipHours {
+		if hour >= 0 && hour < 24 {
+			hours[hour] = true
+		}
+	}
+	var days [7]bool
+	for _, day := range skipDays {
+		if day >= time.Sunday && day <= time.Saturday {
+			days[day] = true
+		}
+	}
+
+	// Working in UTC means every day is 24 hours
+	// long, whatever the local DST rules. A week
+	// of hours is enough to try every combination.
+	t := next.UTC()
+	for i := 0; i <= 7*24; i++ {
+		switch {
+		case days[t.Weekday()]:
+			y, m, d := t.Date()
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+		case hours[t.Hour()]:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		default:
+			return t.In(next.Location())
+		}
+	}
+
+	return next
+}
+
+// parseSkipHours returns the valid hours in
+// a feed's skipHours, sorted and without
+// duplicates.
+func parseSkipHours(hours []int) []int {
+	var seen [24]bool
+	for _, hour := range hours {
+		if hour >= 0 && hour < 24 {
+			seen[hour] = true
+		} else if hour == 24 {
+			// Some feeds count from 1 to 24.
+			seen[0] = true
+		}
+	}
+
+	var out []int
+	for hour, skip := range seen {
+		if skip {
+			out = append(out, hour)
+		}
+	}
+	return out
+}
+
+// parseSkipDays returns the valid days in a
+// feed's skipDays, in order and without
+// duplicates.
+func parseSkipDays(days []string) []time.Weekday {
+	var seen [7]bool
+	for _, day := range days {
+		day = strings.ToLower(strings.TrimSpace(day))
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if day == strings.ToLower(weekday.String()) {
+				seen[weekday] = true
+			}
+		}
+	}
+
+	var out []time.Weekday
+	for weekday, skip := range seen {
+		if skip {
+			out = append(out, time.Weekday(weekday))
+		}
+	}
+	return out
+}
+
+// httpRefresh returns the earliest time that a
+// response with the header h should be fetched
+// again, according to its Cache-Control, Expires
+// and Retry-After headers, or the zero time if
+// none of them are set. The result is clamped to
+// MinRefreshInterval and MaxRefreshInterval.
+func httpRefresh(h http.Header, now time.Time) time.Time {
+	var next time.Time
+	if fresh, ok := freshness(h); ok {
+		next = now.Add(fresh)
+	}
+	if retry := retryAfter(h, now); retry.After(next) {
+		next = retry
+	}
+	if next.IsZero() {
+		return next
+	}
+
+	return clampRefresh(next, now)
+}
+
+// clampRefresh limits the refresh time t to
+// between MinRefreshInterval and MaxRefreshInterval
+// after now. A bound of zero or less is ignored.
+func clampRefresh(t, now time.Time) time.Time {
+	if min := now.Add(MinRefreshInterval); MinRefreshInterval > 0 && t.Before(min) {
+		return min
+	}
+	if max := now.Add(MaxRefreshInterval); MaxRefreshInterval > 0 && t.After(max) {
+		return max
+	}
+	return t
+}
+
+// freshness returns how much longer a response
+// with the header h can be used without being
+// fetched again, and whether h says at all.
+//
+// The max-age directive of Cache-Control takes
+// precedence over Expires, as in RFC 7234.
+//
+// Responses that must be revalidated or have
+// already expired give no hint. Many servers
+// send no-cache for feeds by default, which says
+// nothing about how often they change.
+func freshness(h http.Header) (time.Duration, bool) {
+	var age time.Duration
+	if secs, err := strconv.ParseInt(strings.TrimSpace(h.Get("Age")), 10, 64); err == nil && secs > 0 {
+		age = time.Duration(secs) * time.Second
+	}
+
+	maxAge, noCache := time.Duration(-1), false
+	for _, line := range h["Cache-Control"] {
+		for _, directive := range strings.Split(line, ",") {
+			name, value := directive, ""
+			if i := strings.IndexByte(directive, '='); i >= 0 {
+				name, value = directive[:i], strings.Trim(strings.TrimSpace(directive[i+1:]), `"`)
+			}
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "no-cache", "no-store":
+				noCache = true
+			case "max-age":
+				if secs, err := strconv.ParseInt(value, 10, 64); err == nil && secs >= 0 {
+					maxAge = time.Duration(secs) * time.Second
+				}
+			}
+		}
+	}
+
+	switch {
+	case noCache:
+		return 0, false
+	case maxAge >= 0:
+		return fresh(maxAge - age)
+	}
+
+	value := h.Get("Expires")
+	if value == "" {
+		return 0, false
+	}
+	expires, err := http.ParseTime(value)
+	if err != nil {
+		// Invalid dates, such as "0", mean that
+		// the response has already expired.
+		return 0, false
+	}
+
+	// Measure from the server's Date, so that a
+	// difference between its clock and ours does
+	// not matter.
+	date, err := http.ParseTime(h.Get("Date"))
+	if err != nil {
+		date = time.Now()
+	}
+
+	return fresh(expires.Sub(date) - age)
+}
+
+// fresh returns d, and whether it is long enough
+// to be a hint.
+func fresh(d time.Duration) (time.Duration, bool) {
+	if d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// retryAfter returns the time given by the
+// Retry-After header in h, which may be either
+// a number of seconds or an HTTP date, or the
+// zero time if there is none.
+func retryAfter(h http.Header, now time.Time) time.Time {
+	value := strings.TrimSpace(h.Get("Retry-After"))
+	if value == "" {
+		return time.Time{}
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs < 0 {
+			return time.Time{}
+		}
+		return now.Add(time.Duration(secs) * time.Second)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,246 @@
+package rss
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHTTPRefresh(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Format(http.TimeFormat)
+	tests := []struct {
+		header http.Header
+		want   time.Time
+	}{
+		{http.Header{}, time.Time{}},
+		{http.Header{"Cache-Control": {"public, max-age=3600"}}, now.Add(time.Hour)},
+		{http.Header{"Cache-Control": {`max-age="3600"`}, "Age": {"600"}}, now.Add(50 * time.Minute)},
+		{http.Header{"Cache-Control": {"max-age=3600", "no-cache"}}, time.Time{}},
+		{http.Header{"Cache-Control": {"no-cache"}}, time.Time{}},
+		{http.Header{"Cache-Control": {"private, no-store"}}, time.Time{}},
+		{http.Header{"Cache-Control": {"max-age=0"}}, time.Time{}},
+		{http.Header{"Cache-Control": {"max-age=600"}, "Age": {"900"}}, time.Time{}},
+		{http.Header{"Cache-Control": {"max-age=3600"}, "Expires": {now.Add(2 * time.Hour).Format(http.TimeFormat)}}, now.Add(time.Hour)},
+		{http.Header{"Expires": {now.Add(2 * time.Hour).Format(http.TimeFormat)}, "Date": {date}}, now.Add(2 * time.Hour)},
+		{http.Header{"Expires": {"0"}}, time.Time{}},
+		{http.Header{"Expires": {"not a date"}}, time.Time{}},
+		{http.Header{"Expires": {now.Add(-time.Hour).Format(http.TimeFormat)}, "Date": {date}}, time.Time{}},
+		{http.Header{"Cache-Control": {"no-cache"}, "Retry-After": {"7200"}}, now.Add(2 * time.Hour)},
+		{http.Header{"Cache-Control": {"max-age=60"}, "Retry-After": {"7200"}}, now.Add(2 * time.Hour)},
+		{http.Header{"Cache-Control": {"max-age=5"}}, now.Add(MinRefreshInterval)},
+		{http.Header{"Cache-Control": {"max-age=31536000"}}, now.Add(MaxRefreshInterval)},
+	}
+
+	for _, test := range tests {
+		if got := httpRefresh(test.header, now); !got.Equal(test.want) {
+			t.Errorf("%v: got %v, want %v", test.header, got, test.want)
+		}
+	}
+}
+
+func TestHTTPRefreshExpiresClockSkew(t *testing.T) {
+	// The server's clock is a day behind ours.
+	now := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	server := now.Add(-24 * time.Hour)
+	h := http.Header{
+		"Date":    {server.Format(http.TimeFormat)},
+		"Expires": {server.Add(time.Hour).Format(http.TimeFormat)},
+	}
+
+	if got, want := httpRefresh(h, now), now.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFetchCacheHeaders(t *testing.T) {
+	cacheControl, expires := "max-age=7200", ""
+	notModified := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", cacheControl)
+		if expires != "" {
+			w.Header().Set("Expires", expires)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if notModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		data, _ := ioutil.ReadFile("testdata/atom_1.0")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	feed, err := FetchByClient(server.URL, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRefreshNear(t, "Atom with max-age", feed.Refresh, time.Now().Add(2*time.Hour))
+
+	cacheControl = "max-age=1800"
+	notModified = true
+	feed.Refresh = time.Time{}
+	if err := feed.Update(); err != nil {
+		t.Fatal(err)
+	}
+	assertRefreshNear(t, "304 with max-age", feed.Refresh, time.Now().Add(30*time.Minute))
+
+	cacheControl = ""
+	feed.Refresh = time.Time{}
+	if err := feed.Update(); err != nil {
+		t.Fatal(err)
+	}
+	assertRefreshNear(t, "304 without headers", feed.Refresh, time.Now().Add(DefaultRefreshInterval))
+
+	// Headers saying that the response must be
+	// revalidated, or has already expired, are no
+	// hint, so the feed is not polled constantly.
+	for _, header := range []string{"no-cache", "no-store", "max-age=0", "expires"} {
+		cacheControl = header
+		if header == "expires" {
+			cacheControl = ""
+			expires = "0"
+		}
+		notModified = false
+		feed.Refresh = time.Time{}
+		if err := feed.Update(); err != nil {
+			t.Fatal(err)
+		}
+		assertRefreshNear(t, header, feed.Refresh, time.Now().Add(DefaultRefreshInterval))
+	}
+}
+
+func TestFetchCacheHeadersTTL(t *testing.T) {
+	// rss_2.0 has a ttl of 30 hours, which is later
+	// than the server asks for, so it is kept.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=600")
+		data, _ := ioutil.ReadFile("testdata/rss_2.0")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	feed, err := FetchByClient(server.URL, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Refresh.Before(time.Now().Add(30 * time.Minute)) {
+		t.Errorf("max-age overrode the feed's ttl: refresh at %v", feed.Refresh)
+	}
+}
+
+func assertRefreshNear(t *testing.T, name string, got, want time.Time) {
+	t.Helper()
+	if diff := got.Sub(want); diff < -time.Minute || diff > time.Minute {
+		t.Errorf("%s: got refresh %v, want about %v", name, got, want)
+	}
+}
+
+func TestNextRefresh(t *testing.T) {
+	// 2022-03-14 was a Monday.
+	monday := time.Date(2022, 3, 14, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		now   time.Time
+		ttl   time.Duration
+		hours []int
+		days  []time.Weekday
+		want  time.Time
+	}{
+		{"ttl only", monday, time.Hour, nil, nil, monday.Add(time.Hour)},
+		{"hour not skipped", monday, time.Hour, []int{3}, nil, monday.Add(time.Hour)},
+		{"one hour", monday, time.Hour, []int{11}, nil, time.Date(2022, 3, 14, 12, 0, 0, 0, time.UTC)},
+		{"consecutive hours", monday, time.Hour, []int{11, 12, 13}, nil, time.Date(2022, 3, 14, 14, 0, 0, 0, time.UTC)},
+		{"hours over midnight", monday, 13 * time.Hour, []int{22, 23, 0, 1}, nil, time.Date(2022, 3, 15, 2, 0, 0, 0, time.UTC)},
+		{"day", monday, time.Hour, nil, []time.Weekday{time.Monday}, time.Date(2022, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"days then hours", monday, time.Hour, []int{0, 1, 2}, []time.Weekday{time.Monday, time.Tuesday}, time.Date(2022, 3, 16, 3, 0, 0, 0, time.UTC)},
+		{"weekend", time.Date(2022, 3, 19, 8, 0, 0, 0, time.UTC), 0, nil, []time.Weekday{time.Saturday, time.Sunday}, time.Date(2022, 3, 21, 0, 0, 0, 0, time.UTC)},
+		{"every hour", monday, time.Hour, allHours(), nil, monday.Add(time.Hour)},
+		{"every day", monday, time.Hour, nil, []time.Weekday{0, 1, 2, 3, 4, 5, 6}, monday.Add(time.Hour)},
+	}
+
+	for _, test := range tests {
+		if got := nextRefresh(test.now, test.ttl, test.hours, test.days); !got.Equal(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNextRefreshLocalTime(t *testing.T) {
+	// Skip hours are in UTC, whatever the local time
+	// zone, and are unaffected by DST changes. In New
+	// York, clocks went forward at 2am on 2022-03-13.
+	loc := time.FixedZone("EST", -5*60*60)
+	if ny, err := time.LoadLocation("America/New_York"); err == nil {
+		loc = ny
+	}
+	now := time.Date(2022, 3, 12, 20, 0, 0, 0, loc) // Saturday, but 01:00 UTC on Sunday.
+
+	got := nextRefresh(now, 0, []int{1, 2, 3, 4, 5, 6, 7}, []time.Weekday{time.Saturday})
+	want := time.Date(2022, 3, 13, 8, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got.Location() != loc {
+		t.Errorf("got location %v, want %v", got.Location(), loc)
+	}
+}
+
+func allHours() []int {
+	hours := make([]int, 24)
+	for i := range hours {
+		hours[i] = i
+	}
+	return hours
+}
+
+func TestParseRefreshHints(t *testing.T) {
+	data := `<rss version="2.0"><channel>
+		<title>Hints</title>
+		<ttl>60</ttl>
+		<skipHours><hour>5</hour><hour>3</hour><hour>24</hour><hour>3</hour><hour>99</hour></skipHours>
+		<skipDays><day>Sunday</day><day> saturday </day><day>Someday</day></skipDays>
+	</channel></rss>`
+
+	feed, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if feed.TTL != time.Hour {
+		t.Errorf("got TTL %v, want 1h", feed.TTL)
+	}
+	if !reflect.DeepEqual(feed.SkipHours, []int{0, 3, 5}) {
+		t.Errorf("got skip hours %v", feed.SkipHours)
+	}
+	if !reflect.DeepEqual(feed.SkipDays, []time.Weekday{time.Sunday, time.Saturday}) {
+		t.Errorf("got skip days %v", feed.SkipDays)
+	}
+
+	next := feed.Refresh.UTC()
+	if next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+		t.Errorf("refresh %v is on a skipped day", feed.Refresh)
+	}
+	for _, hour := range feed.SkipHours {
+		if next.Hour() == hour {
+			t.Errorf("refresh %v is in a skipped hour", feed.Refresh)
+		}
+	}
+
+	// The hints survive being written out again.
+	buf := new(bytes.Buffer)
+	if err := feed.WriteRSS2(buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TTL != feed.TTL || !reflect.DeepEqual(got.SkipHours, feed.SkipHours) || !reflect.DeepEqual(got.SkipDays, feed.SkipDays) {
+		t.Errorf("got hints %v %v %v after writing, want %v %v %v", got.TTL, got.SkipHours, got.SkipDays, feed.TTL, feed.SkipHours, feed.SkipDays)
+	}
+}
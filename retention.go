@@ -0,0 +1,143 @@
+package rss
+
+import (
+	"sort"
+	"time"
+)
+
+// Retention limits the items kept by a long-lived
+// Feed. Items that are unread or starred are never
+// pruned, whatever the limits.
+type Retention struct {
+	// MaxItems is the most items to keep, preferring
+	// the newest. Zero means no limit.
+	MaxItems int
+
+	// MaxAge is how long to keep items after their
+	// Date. Items without a valid date are not pruned
+	// for their age. Zero means no limit.
+	MaxAge time.Duration
+
+	// MaxSeen is the most IDs of pruned items that
+	// are remembered, so that they are not added
+	// again as new if they are still in the feed's
+	// document. If zero, it defaults to 1000.
+	MaxSeen int
+}
+
+// DefaultRetention is used by Feed.Update for feeds
+// that have no Retention. If it is nil, as it is by
+// default, such feeds keep every item.
+var DefaultRetention *Retention
+
+// Prune removes items from f according to r, and
+// returns their IDs. If f has a Store, they are
+// removed from it too.
+func (f *Feed) Prune(r *Retention) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.buildItemMap()
+	pruned := f.prune(r, time.Now())
+	if f.Store != nil && len(pruned) > 0 {
+		// A failure is retried by the next update.
+		f.saveChanges(&Changes{Pruned: pruned}, f.UpdateURL)
+	}
+
+	return pruned
+}
+
+// prune is like Prune, but must be called with
+// f.mu held and f.ItemMap built.
+func (f *Feed) prune(r *Retention, now time.Time) []string {
+	if r == nil || (r.MaxItems <= 0 && r.MaxAge <= 0) {
+		return nil
+	}
+
+	drop := make(map[*Item]bool)
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge)
+		for _, item := range f.Items {
+			if item.prunable() && item.DateValid && item.Date.Before(cutoff) {
+				drop[item] = true
+			}
+		}
+	}
+
+	if r.MaxItems > 0 && len(f.Items)-len(drop) > r.MaxItems {
+		// Order the items oldest first. Items without
+		// a date count as older than those with one,
+		// and otherwise later items are newer.
+		oldest := make([]*Item, len(f.Items))
+		copy(oldest, f.Items)
+		sort.SliceStable(oldest, func(i, j int) bool {
+			a, b := oldest[i], oldest[j]
+			if a.DateValid != b.DateValid {
+				return !a.DateValid
+			}
+			return a.DateValid && a.Date.Before(b.Date)
+		})
+
+		excess := len(f.Items) - len(drop) - r.MaxItems
+		for _, item := range oldest {
+			if excess == 0 {
+				break
+			}
+			if item.prunable() && !drop[item] {
+				drop[item] = true
+				excess--
+			}
+		}
+	}
+
+	if len(drop) == 0 {
+		return nil
+	}
+
+	var pruned []string
+	kept := make([]*Item, 0, len(f.Items)-len(drop))
+	for _, item := range f.Items {
+		if !drop[item] {
+			kept = append(kept, item)
+			continue
+		}
+		pruned = append(pruned, item.ID)
+		delete(f.ItemMap, item.ID)
+	}
+	f.Items = kept
+
+	f.Seen = append(f.Seen, pruned...)
+	maxSeen := r.MaxSeen
+	if maxSeen <= 0 {
+		maxSeen = 1000
+	}
+	if extra := len(f.Seen) - maxSeen; extra > 0 {
+		f.Seen = append(f.Seen[:0:0], f.Seen[extra:]...)
+	}
+
+	return pruned
+}
+
+// prunable reports whether the item can be
+// pruned by a Retention.
+func (i *Item) prunable() bool {
+	return i.Read && !i.Starred
+}
+
+// SetStarred stars or unstars the item with the
+// given ID, reporting whether f has such an item.
+// Starred items are never pruned.
+func (f *Feed) SetStarred(id string, starred bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var items []*Item
+	for i, item := range f.Items {
+		if item.ID == id {
+			items = append(items, f.editItem(i, func(item *Item) { item.Starred = starred }))
+		}
+	}
+	f.saveItems(items)
+
+	return len(items) > 0
+}
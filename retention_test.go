@@ -0,0 +1,98 @@
+package rss
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	feed := feedWithItemsEvery(6, 24*time.Hour, now)
+	for i, item := range feed.Items {
+		item.ID = string(rune('a' + i))
+		item.Read = true
+	}
+	feed.Items[4].Read = false
+	feed.Items[5].Starred = true
+
+	// Items a to f are 1 to 6 days old. e is
+	// unread and f is starred, so both are kept.
+	pruned := feed.Prune(&Retention{MaxAge: 60 * time.Hour})
+	if want := []string{"c", "d"}; !reflect.DeepEqual(pruned, want) {
+		t.Errorf("MaxAge: got %v pruned, want %v", pruned, want)
+	}
+
+	pruned = feed.Prune(&Retention{MaxItems: 3})
+	if want := []string{"b"}; !reflect.DeepEqual(pruned, want) {
+		t.Errorf("MaxItems: got %v pruned, want %v", pruned, want)
+	}
+
+	var ids []string
+	for _, item := range feed.Items {
+		ids = append(ids, item.ID)
+	}
+	if want := []string{"a", "e", "f"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("kept %v, want %v", ids, want)
+	}
+	if _, ok := feed.ItemMap["b"]; ok {
+		t.Errorf("pruned item is still in the item map")
+	}
+
+	if !feed.SetStarred("a", true) || feed.SetStarred("b", true) {
+		t.Errorf("SetStarred did not report the items it found")
+	}
+	if pruned := feed.Prune(&Retention{MaxItems: 1}); len(pruned) != 0 {
+		t.Errorf("pruned unread or starred items %v", pruned)
+	}
+
+	// Only the most recently pruned IDs are kept.
+	feed.SetStarred("a", false)
+	feed.Prune(&Retention{MaxItems: 1, MaxSeen: 3})
+	if want := []string{"d", "b", "a"}; !reflect.DeepEqual(feed.Seen, want) {
+		t.Errorf("got seen IDs %v, want %v", feed.Seen, want)
+	}
+}
+
+func TestUpdateRetention(t *testing.T) {
+	doc := `<rss version="2.0"><channel><ttl>-1</ttl>
+		<item><guid>1</guid><pubDate>Mon, 14 Mar 2022 10:00:00 GMT</pubDate></item>
+		<item><guid>2</guid><pubDate>Mon, 14 Mar 2022 09:00:00 GMT</pubDate></item>
+	</channel></rss>`
+	fetch := func(url string) (*http.Response, error) {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(doc))}, nil
+	}
+
+	feed, err := FetchByFunc(fetch, "http://example.com/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.Retention = &Retention{MaxItems: 1}
+	feed.MarkAllRead()
+
+	changes, err := feed.UpdateChanges(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes.Pruned, []string{"2"}) || len(changes.Added) != 0 {
+		t.Errorf("got changes %+v, want item 2 pruned", changes)
+	}
+
+	// The pruned item is still in the document,
+	// but is not added again.
+	doc = strings.Replace(doc, "<item>", `<item><guid>3</guid><pubDate>Mon, 14 Mar 2022 11:00:00 GMT</pubDate></item><item>`, 1)
+	changes, err = feed.UpdateChanges(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes.Added, []string{"3"}) || !reflect.DeepEqual(changes.Pruned, []string{"1"}) {
+		t.Errorf("got changes %+v, want item 3 added and item 1 pruned", changes)
+	}
+	if len(feed.Items) != 1 || feed.Unread != 1 {
+		t.Errorf("got %d items and %d unread, want 1 and 1", len(feed.Items), feed.Unread)
+	}
+}
@@ -2,123 +2,355 @@ package rss // import "github.com/SlyMarbo/rss"
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"net"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 )
 
-// Parse RSS or Atom data.
+// Parse RSS, Atom or JSON Feed data.
+//
+// The format is determined from the document's
+// root element and its namespace, or from the
+// opening brace of a JSON object.
 func Parse(data []byte) (*Feed, error) {
-
-	if strings.Contains(string(data), "<rss") {
-		if debug {
-			fmt.Println("[i] Parsing as RSS 2.0")
-		}
-		return parseRSS2(data)
-	} else if strings.Contains(string(data), "xmlns=\"http://purl.org/rss/1.0/\"") {
-		if debug {
-			fmt.Println("[i] Parsing as RSS 1.0")
-		}
-		return parseRSS1(data)
-	} else {
-		if debug {
-			fmt.Println("[i] Parsing as Atom")
-		}
-		return parseAtom(data)
-	}
+	return ParseReader(bytes.NewReader(data))
 }
 
+// Format identifies the syntax of a feed.
+type Format string
+
+// Feed formats.
+const (
+	FormatRSS1 Format = "RSS 1.0"
+	FormatRSS2 Format = "RSS 2.0"
+	FormatAtom Format = "Atom"
+	FormatJSON Format = "JSON Feed"
+)
+
 // A FetchFunc is a function that fetches a feed for given URL.
 type FetchFunc func(url string) (resp *http.Response, err error)
 
 // DefaultFetchFunc uses http.DefaultClient to fetch a feed.
-var DefaultFetchFunc = func(url string) (resp *http.Response, err error) {
+//
+// If it is replaced, Fetch and Update use it for feeds
+// with no FetchFunc or RequestFunc. Otherwise they use
+// DefaultRequestFunc, which can make conditional
+// requests and be cancelled, so prefer replacing that.
+var DefaultFetchFunc = defaultFetchFunc
+
+func defaultFetchFunc(url string) (resp *http.Response, err error) {
 	client := http.DefaultClient
 	return client.Get(url)
 }
 
+// replacedFetchFunc returns DefaultFetchFunc if it
+// has been replaced, or nil if not.
+func replacedFetchFunc() FetchFunc {
+	if DefaultFetchFunc == nil || reflect.ValueOf(DefaultFetchFunc).Pointer() == reflect.ValueOf(defaultFetchFunc).Pointer() {
+		return nil
+	}
+	return DefaultFetchFunc
+}
+
+// A RequestFunc is a function that performs an HTTP
+// request for a feed. Unlike a FetchFunc, it receives
+// the whole request, so that any headers set by the
+// package, such as those used for conditional
+// requests, are sent, and so that the request can be
+// cancelled through its context.
+type RequestFunc func(req *http.Request) (resp *http.Response, err error)
+
+// DefaultRequestFunc uses http.DefaultClient to fetch a feed.
+var DefaultRequestFunc = func(req *http.Request) (resp *http.Response, err error) {
+	client := http.DefaultClient
+	return client.Do(req)
+}
+
 // Fetch downloads and parses the RSS feed at the given URL
 func Fetch(url string) (*Feed, error) {
-	return FetchByFunc(DefaultFetchFunc, url)
+	return FetchContext(context.Background(), url)
+}
+
+// FetchContext downloads and parses the RSS feed at
+// the given URL. The request is cancelled if ctx is
+// done before it completes.
+func FetchContext(ctx context.Context, url string) (*Feed, error) {
+	if fetchFunc := replacedFetchFunc(); fetchFunc != nil {
+		out, err := fetchByFunc(ctx, fetchFunc, url)
+		if err != nil {
+			return nil, err
+		}
+		out.setDefaultRefresh(time.Now())
+		return out, nil
+	}
+	return FetchByRequestFuncContext(ctx, DefaultRequestFunc, url)
 }
 
 // FetchByClient uses a http.Client to fetch a URL.
 func FetchByClient(url string, client *http.Client) (*Feed, error) {
-	fetchFunc := func(url string) (resp *http.Response, err error) {
-		return client.Get(url)
-	}
-	return FetchByFunc(fetchFunc, url)
+	return FetchByRequestFunc(client.Do, url)
 }
 
 // FetchByFunc uses a func to fetch a URL.
 func FetchByFunc(fetchFunc FetchFunc, url string) (*Feed, error) {
+	out, err := fetchByFunc(context.Background(), fetchFunc, url)
+	if err != nil {
+		return nil, err
+	}
+
+	out.setDefaultRefresh(time.Now())
+
+	return out, nil
+}
+
+// fetchByFunc fetches url with fetchFunc. As a
+// FetchFunc has no context, ctx is only checked
+// before the request is made.
+func fetchByFunc(ctx context.Context, fetchFunc FetchFunc, url string) (*Feed, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	resp, err := fetchFunc(url)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	out, err := readFeed(resp, url)
+	if err != nil {
+		return nil, err
+	}
+
+	out.FetchFunc = fetchFunc
+
+	return out, nil
+}
+
+// FetchByRequestFunc uses a func to fetch a URL.
+func FetchByRequestFunc(requestFunc RequestFunc, url string) (*Feed, error) {
+	return FetchByRequestFuncContext(context.Background(), requestFunc, url)
+}
+
+// FetchByRequestFuncContext uses a func to fetch a URL.
+// The request passed to requestFunc carries ctx.
+func FetchByRequestFuncContext(ctx context.Context, requestFunc RequestFunc, url string) (*Feed, error) {
+	out, err := fetchByRequestFunc(ctx, requestFunc, url, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	out.RequestFunc = requestFunc
+	out.setDefaultRefresh(time.Now())
+
+	return out, nil
+}
+
+// fetchByRequestFunc fetches url, sending any
+// validators from a previous response so that
+// the server can reply with 304 Not Modified.
+func fetchByRequestFunc(ctx context.Context, requestFunc RequestFunc, url, etag, lastModified string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := requestFunc(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return readFeed(resp, url)
+}
+
+// readFeed parses the feed in resp, which
+// was fetched from url.
+func readFeed(resp *http.Response, url string) (*Feed, error) {
+	defer resp.Body.Close()
+
+	now := time.Now()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		// The caller may still need to know when to
+		// check again.
+		return &Feed{Refresh: httpRefresh(resp.Header, now)}, ErrNotModified
+	case resp.StatusCode == 0:
+		// Responses built by a FetchFunc may not
+		// set a status; treat them as successful.
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, &HTTPStatusError{
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+			RetryAfter: retryAfter(resp.Header, now),
+		}
+	}
 
-	out, err := Parse(body)
+	out, err := parseReader(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	// Wait for whichever is later of the feed's
+	// own refresh time and the server's. If neither
+	// gives one, Refresh is left for the caller.
+	if next := httpRefresh(resp.Header, now); next.After(out.Refresh) {
+		out.Refresh = next
+	}
+
 	if out.Link == "" {
 		out.Link = url
 	}
 
 	out.UpdateURL = url
-	out.FetchFunc = fetchFunc
+	out.Redirect = redirectOf(resp, url)
+	out.ETag = resp.Header.Get("ETag")
+	out.LastModified = resp.Header.Get("Last-Modified")
 
 	return out, nil
 }
 
-// Feed is the top-level structure.
-type Feed struct {
-	Nickname    string              `json:"nickname"` // This is not set by the package, but could be helpful.
-	Title       string              `json:"title"`
-	Language    string              `json:"language"`
-	Author      string              `json:"author"`
-	Description string              `json:"description"`
-	Link        string              `json:"link"`      // Link to the creator's website.
-	UpdateURL   string              `json:"updateurl"` // URL of the feed itself.
-	Image       *Image              `json:"image"`     // Feed icon.
-	Categories  []string            `json:"categories"`
-	Items       []*Item             `json:"items"`
-	ItemMap     map[string]struct{} `json:"itemmap"` // Used in checking whether an item has been seen before.
-	Refresh     time.Time           `json:"refresh"` // Earliest time this feed should next be checked.
-	Unread      uint32              `json:"unread"`  // Number of unread items. Used by aggregators.
-	FetchFunc   FetchFunc           `json:"-"`
+// Redirect describes the redirects that were
+// followed to fetch a feed.
+type Redirect struct {
+	URL       string // URL the feed was fetched from in the end.
+	Permanent bool   // Whether every redirect was permanent (301 or 308).
 }
 
-type refreshError string
+// redirectOf returns the redirects followed
+// to get resp, which was requested from url,
+// or nil if there were none.
+func redirectOf(resp *http.Response, url string) *Redirect {
+	if resp.Request == nil || resp.Request.Response == nil {
+		return nil
+	}
 
-var _ net.Error = refreshError("")
+	out := &Redirect{URL: resp.Request.URL.String(), Permanent: true}
+	if out.URL == url {
+		return nil
+	}
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		default:
+			out.Permanent = false
+		}
+	}
 
-func (r refreshError) Error() string {
-	return string(r)
+	return out
 }
 
-func (r refreshError) Timeout() bool {
-	return false
-}
+// plainFeed is a Feed without its methods, so that
+// it can be marshalled while f.mu is held.
+type plainFeed Feed
+
+// MarshalJSON encodes f as JSON, holding its read
+// lock so that it is safe while f is being updated.
+func (f *Feed) MarshalJSON() ([]byte, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 
-func (r refreshError) Temporary() bool {
-	return true
+	return json.Marshal((*plainFeed)(f))
 }
 
-var errUpdateNotReady refreshError = "not ready to update: too soon to refresh"
+// MovedFunc is called when a feed is found to have
+// moved permanently from oldURL to f.UpdateURL.
+type MovedFunc func(f *Feed, oldURL string)
+
+// Feed is the top-level structure.
+//
+// A Feed's methods are safe for concurrent use,
+// including MarshalJSON, so a feed can be saved
+// with json.Marshal while it is being updated.
+// Its fields can be read or changed directly only
+// while no other goroutine is using the feed. The
+// exceptions are the items returned by methods such
+// as UnreadItems, which are never changed and can
+// be read at any time, and Nickname, FetchFunc,
+// RequestFunc, RefreshPolicy, Retention and Moved,
+// which the package only reads.
+type Feed struct {
+	Nickname     string              `json:"nickname"` // This is not set by the package, but could be helpful.
+	Format       Format              `json:"format"`   // Syntax the feed was parsed from.
+	Title        string              `json:"title"`
+	Language     string              `json:"language"`
+	Author       string              `json:"author"`
+	Description  string              `json:"description"`
+	Link         string              `json:"link"`      // Link to the creator's website.
+	UpdateURL    string              `json:"updateurl"` // URL of the feed itself.
+	Image        *Image              `json:"image"`     // Feed icon.
+	Categories   []string            `json:"categories"`
+	Podcast      *Podcast            `json:"podcast,omitempty"`      // iTunes podcast metadata.
+	PodcastIndex *PodcastIndex       `json:"podcastindex,omitempty"` // Podcasting 2.0 metadata.
+	DublinCore   *DublinCore         `json:"dublincore,omitempty"`
+	Items        []*Item             `json:"items"`
+	ItemMap      map[string]struct{} `json:"itemmap"`             // Used in checking whether an item has been seen before.
+	Seen         []string            `json:"seen,omitempty"`      // IDs of items pruned by a Retention, oldest first.
+	Refresh      time.Time           `json:"refresh"`             // Earliest time this feed should next be checked.
+	TTL          time.Duration       `json:"ttl,omitempty"`       // How long the feed can be cached, from <ttl>.
+	SkipHours    []int               `json:"skiphours,omitempty"` // Hours (UTC) in which not to check the feed.
+	SkipDays     []time.Weekday      `json:"skipdays,omitempty"`  // Days (UTC) on which not to check the feed.
+	Unread       uint32              `json:"unread"`              // Number of unread items. Used by aggregators.
+	Failures     uint32              `json:"failures,omitempty"`  // Number of updates that have failed in a row.
+	FetchFunc    FetchFunc           `json:"-"`
+	RequestFunc  RequestFunc         `json:"-"`
+
+	// RefreshPolicy, if set, decides the Refresh
+	// time after each update in place of
+	// DefaultRefreshPolicy.
+	RefreshPolicy RefreshPolicy `json:"-"`
+
+	// Retention, if set, limits the items kept
+	// after each update in place of DefaultRetention.
+	Retention *Retention `json:"-"`
+
+	// Store, if set, is where the feed saves its
+	// changes, so that they survive restarts.
+	Store Store `json:"-"`
+
+	// Redirect is set if redirects were followed
+	// on the last fetch.
+	Redirect *Redirect `json:"-"`
+
+	// Moved, if set, is called when an update
+	// follows permanent redirects and UpdateURL
+	// is changed to the feed's new location.
+	Moved MovedFunc `json:"-"`
+
+	// ETag and LastModified hold the validators
+	// sent with the last response, which are used
+	// to make conditional requests on update.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastmodified,omitempty"`
+
+	// Extensions holds the custom extension objects
+	// of a JSON Feed, keyed by their underscore-prefixed
+	// names.
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+
+	// mu guards the fields above against concurrent
+	// use by the feed's methods. updating serialises
+	// updates, so that mu is not held while the feed
+	// is being fetched.
+	mu       sync.RWMutex
+	updating sync.Mutex
+	unsaved  bool // Whether a write to Store has failed.
+}
 
 // DefaultRefreshInterval is the minimum
 // wait until the next refresh, provided
@@ -131,59 +363,326 @@ var errUpdateNotReady refreshError = "not ready to update: too soon to refresh"
 // the feed hosts.
 //
 // The default value is 12 hours.
-//
 var DefaultRefreshInterval = 12 * time.Hour
 
+// MinRefreshInterval and MaxRefreshInterval bound
+// the refresh times that servers ask for with the
+// Cache-Control, Expires and Retry-After headers,
+// so that a misconfigured server cannot have its
+// feed fetched constantly or never again. Either
+// can be set to zero to remove the bound.
+var (
+	MinRefreshInterval = time.Minute
+	MaxRefreshInterval = 7 * 24 * time.Hour
+)
+
 // Update fetches any new items and updates f.
+// Items that are already in f are replaced if
+// they have been edited, but keep their Read
+// state. See UpdateChanges and Merge.
+//
+// If f has a RequestFunc, it is used to make a
+// conditional request, so that an unchanged feed
+// is not downloaded again. Otherwise, f.FetchFunc
+// is used if set. A feed with neither, such as one
+// loaded from JSON or a Store, uses DefaultFetchFunc
+// if it has been replaced, and DefaultRequestFunc
+// if not.
+//
+// If every redirect followed was permanent (301
+// or 308), f.UpdateURL is changed to the new URL
+// and f.Moved is called. Temporary redirects are
+// recorded in f.Redirect but do not change it.
 func (f *Feed) Update() error {
-	if f.FetchFunc == nil {
-		f.FetchFunc = DefaultFetchFunc
+	return f.UpdateContext(context.Background())
+}
+
+// UpdateContext is like Update, but the request is
+// cancelled if ctx is done before it completes. A
+// FetchFunc cannot be cancelled once it has been
+// called, so leave f.FetchFunc unset or set
+// f.RequestFunc to make use of this.
+func (f *Feed) UpdateContext(ctx context.Context) error {
+	_, err := f.UpdateChanges(ctx)
+	return err
+}
+
+// UpdateChanges is like UpdateContext, but also
+// reports how the update changed f. If the feed
+// was not modified, the changes are empty. If f
+// has a Store and the changes could not be saved
+// to it, they are returned along with the error.
+func (f *Feed) UpdateChanges(ctx context.Context) (*Changes, error) {
+	f.mu.RLock()
+	requestFunc, fetchFunc := f.RequestFunc, f.FetchFunc
+	f.mu.RUnlock()
+
+	if requestFunc == nil && fetchFunc == nil {
+		fetchFunc = replacedFetchFunc()
+		if fetchFunc == nil {
+			requestFunc = DefaultRequestFunc
+		}
 	}
-	return f.UpdateByFunc(f.FetchFunc)
+	if requestFunc != nil {
+		return f.update(func(url, etag, lastModified string) (*Feed, error) {
+			return fetchByRequestFunc(ctx, requestFunc, url, etag, lastModified)
+		})
+	}
+	return f.update(func(url, _, _ string) (*Feed, error) {
+		return fetchByFunc(ctx, fetchFunc, url)
+	})
 }
 
 // UpdateByFunc uses a func to update f.
+//
+// A FetchFunc cannot send request headers, so
+// this always downloads the whole feed. See
+// UpdateByRequestFunc.
 func (f *Feed) UpdateByFunc(fetchFunc FetchFunc) error {
+	_, err := f.update(func(url, _, _ string) (*Feed, error) {
+		return fetchByFunc(context.Background(), fetchFunc, url)
+	})
+	return err
+}
+
+// UpdateByRequestFunc uses a func to update f.
+//
+// The request includes If-None-Match and
+// If-Modified-Since headers from the previous
+// response. If the server replies with 304
+// Not Modified, f is left unchanged apart from
+// its Refresh time, and no error is returned.
+func (f *Feed) UpdateByRequestFunc(requestFunc RequestFunc) error {
+	return f.UpdateByRequestFuncContext(context.Background(), requestFunc)
+}
+
+// UpdateByRequestFuncContext is like UpdateByRequestFunc,
+// but the request passed to requestFunc carries ctx.
+func (f *Feed) UpdateByRequestFuncContext(ctx context.Context, requestFunc RequestFunc) error {
+	_, err := f.update(func(url, etag, lastModified string) (*Feed, error) {
+		return fetchByRequestFunc(ctx, requestFunc, url, etag, lastModified)
+	})
+	return err
+}
+
+// update fetches the feed with fetch, which is
+// passed its URL and validators, and merges the
+// result into f.
+func (f *Feed) update(fetch func(url, etag, lastModified string) (*Feed, error)) (*Changes, error) {
+	f.updating.Lock()
+	defer f.updating.Unlock()
+
+	now := time.Now()
+
+	f.mu.Lock()
 
 	// Check that we don't update too often.
-	if f.Refresh.After(time.Now()) {
-		return errUpdateNotReady
+	if refresh := f.Refresh; refresh.After(now) {
+		f.mu.Unlock()
+		return nil, &TooSoonError{Refresh: refresh}
 	}
 
 	if f.UpdateURL == "" {
-		return errors.New("feed has no URL")
+		f.mu.Unlock()
+		return nil, ErrNoURL
 	}
 
-	if f.ItemMap == nil {
-		f.ItemMap = make(map[string]struct{})
-		for _, item := range f.Items {
-			if _, ok := f.ItemMap[item.ID]; !ok {
-				f.ItemMap[item.ID] = struct{}{}
-			}
+	f.buildItemMap()
+
+	url, etag, lastModified := f.UpdateURL, f.ETag, f.LastModified
+	f.mu.Unlock()
+
+	update, err := fetch(url, etag, lastModified)
+
+	f.mu.Lock()
+	hint := f.refreshHint(now, update, err)
+	changes := new(Changes)
+	oldURL := f.UpdateURL
+	switch {
+	case errors.Is(err, ErrNotModified):
+		err = nil
+	case err == nil:
+		changes = f.merge(update)
+		retention := f.Retention
+		if retention == nil {
+			retention = DefaultRetention
 		}
+		changes.Pruned = f.prune(retention, now)
 	}
 
-	update, err := FetchByFunc(fetchFunc, f.UpdateURL)
-	if err != nil {
-		return err
+	if err == nil {
+		f.Failures = 0
+	} else if !errors.Is(err, context.Canceled) {
+		f.Failures++
+	}
+
+	policy := f.RefreshPolicy
+	if policy == nil {
+		policy = DefaultRefreshPolicy
+	}
+	if policy == nil {
+		policy = defaultRefreshPolicy{}
+	}
+	f.Refresh = policy.NextRefresh(f, now, hint, err)
+
+	// Save after failed updates too, as the
+	// Refresh time and Failures have changed.
+	if f.Store != nil {
+		if serr := f.saveChanges(changes, oldURL); serr != nil && err == nil {
+			err = fmt.Errorf("saving feed: %w", serr)
+		}
+	}
+
+	moved := f.Moved
+	if f.UpdateURL == oldURL {
+		moved = nil
+	}
+	f.mu.Unlock()
+
+	// Called without f locked, so that it can
+	// use f's methods.
+	if moved != nil {
+		moved(f, oldURL)
+	}
+
+	return changes, err
+}
+
+// refreshHint returns the earliest time to check
+// f again that the feed or its server asked for,
+// given the result of an update at now, or the
+// zero time if they did not say.
+func (f *Feed) refreshHint(now time.Time, update *Feed, err error) time.Time {
+	var status *HTTPStatusError
+	switch {
+	case err == nil:
+		return update.Refresh
+	case errors.Is(err, ErrNotModified):
+		// Schedule the feed as if it had been
+		// downloaded again.
+		var hint time.Time
+		if f.TTL > 0 {
+			hint = nextRefresh(now, f.TTL, f.SkipHours, f.SkipDays)
+		}
+		if update != nil && update.Refresh.After(hint) {
+			hint = update.Refresh
+		}
+		return hint
+	case errors.As(err, &status) && !status.RetryAfter.IsZero():
+		return clampRefresh(status.RetryAfter, now)
+	}
+
+	return time.Time{}
+}
+
+// MarkRead marks the item with the given ID as
+// read, reporting whether f has such an item.
+func (f *Feed) MarkRead(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	found := false
+	for i, item := range f.Items {
+		if item.ID == id {
+			f.editItem(i, func(item *Item) { item.Read = true })
+			found = true
+		}
+	}
+	f.countUnread()
+	if found {
+		f.saveRead([]string{id}, true)
 	}
 
-	f.Refresh = update.Refresh
-	f.Title = update.Title
-	f.Description = update.Description
+	return found
+}
+
+// MarkAllRead marks every item in f as read.
+func (f *Feed) MarkAllRead() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ids []string
+	for i, item := range f.Items {
+		if !item.Read {
+			ids = append(ids, item.ID)
+			f.editItem(i, func(item *Item) { item.Read = true })
+		}
+	}
+	f.Unread = 0
+	f.saveRead(ids, true)
+}
+
+// UnreadItems returns the items in f that have
+// not been read.
+func (f *Feed) UnreadItems() []*Item {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var out []*Item
+	for _, item := range f.Items {
+		if !item.Read {
+			out = append(out, item)
+		}
+	}
+	return out
+}
 
-	for _, item := range update.Items {
-		if _, ok := f.ItemMap[item.ID]; !ok {
-			f.Items = append(f.Items, item)
-			f.ItemMap[item.ID] = struct{}{}
+// editItem replaces f.Items[i] with a copy that
+// has been changed by edit, returning the copy.
+// Items are never changed in place, so that they
+// can be read by callers without holding f.mu.
+// It must be called with f.mu held.
+func (f *Feed) editItem(i int, edit func(*Item)) *Item {
+	item := *f.Items[i]
+	edit(&item)
+	f.Items[i] = &item
+	return &item
+}
+
+// countUnread sets f.Unread from the items'
+// Read fields. It must be called with f.mu held.
+func (f *Feed) countUnread() {
+	f.Unread = 0
+	for _, item := range f.Items {
+		if !item.Read {
 			f.Unread++
 		}
 	}
+}
+
+// addItems adds newly parsed items to f, skipping
+// any with an ID that has already been seen. It
+// returns the number of items added.
+func (f *Feed) addItems(items []*Item) int {
+	if f.ItemMap == nil {
+		f.ItemMap = make(map[string]struct{})
+	}
+	if f.Items == nil {
+		f.Items = make([]*Item, 0, len(items))
+	}
+
+	added := 0
+	for _, item := range items {
+		if _, ok := f.ItemMap[item.ID]; ok {
+			if debug {
+				fmt.Printf("[w] Item %q has duplicate ID.\n", item.Title)
+			}
+			continue
+		}
+
+		f.Items = append(f.Items, item)
+		f.ItemMap[item.ID] = struct{}{}
+		f.Unread++
+		added++
+	}
 
-	return nil
+	return added
 }
 
 func (f *Feed) String() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	buf := new(bytes.Buffer)
 	if debug {
 		w := tabwriter.NewWriter(buf, 0, 8, 0, '\t', tabwriter.StripEscape)
@@ -193,7 +692,11 @@ func (f *Feed) String() string {
 		fmt.Fprintf(w, "\xff\t\xffDescription:\t%q\n", f.Description)
 		fmt.Fprintf(w, "\xff\t\xffLink:\t%q\n", f.Link)
 		fmt.Fprintf(w, "\xff\t\xffUpdateURL:\t%q\n", f.UpdateURL)
-		fmt.Fprintf(w, "\xff\t\xffImage:\t%q (%s)\n", f.Image.Title, f.Image.URL)
+		if f.Image != nil {
+			fmt.Fprintf(w, "\xff\t\xffImage:\t%q (%s)\n", f.Image.Title, f.Image.URL)
+		} else {
+			fmt.Fprintf(w, "\xff\t\xffImage:\t<nil>\n")
+		}
 		fmt.Fprintf(w, "\xff\t\xffRefresh:\t%s\n", f.Refresh.Format(DATE))
 		fmt.Fprintf(w, "\xff\t\xffUnread:\t%d\n", f.Unread)
 		fmt.Fprintf(w, "\xff\t\xffItems:\t(%d) {\n", len(f.Items))
@@ -220,17 +723,27 @@ func (f *Feed) String() string {
 
 // Item represents a single story.
 type Item struct {
-	Title      string    `json:"title"`
-	Summary    string    `json:"summary"`
-	Content    string    `json:"content"`
-	Categories []string  `json:"category"`
-	Link       string    `json:"link"`
-	Date       time.Time `json:"date"`
-	Image      *Image    `json:"image"`
-	DateValid  bool
-	ID         string       `json:"id"`
-	Enclosures []*Enclosure `json:"enclosures"`
-	Read       bool         `json:"read"`
+	Title        string    `json:"title"`
+	Summary      string    `json:"summary"`
+	Content      string    `json:"content"`
+	Categories   []string  `json:"category"`
+	Link         string    `json:"link"`
+	Author       string    `json:"author"`
+	Date         time.Time `json:"date"`
+	Image        *Image    `json:"image"`
+	DateValid    bool
+	ID           string        `json:"id"`
+	Enclosures   []*Enclosure  `json:"enclosures"`
+	Read         bool          `json:"read"`
+	Starred      bool          `json:"starred,omitempty"`
+	Podcast      *Podcast      `json:"podcast,omitempty"`      // iTunes episode metadata.
+	PodcastIndex *PodcastIndex `json:"podcastindex,omitempty"` // Podcasting 2.0 episode metadata.
+	Media        *Media        `json:"media,omitempty"`        // Media RSS metadata.
+	DublinCore   *DublinCore   `json:"dublincore,omitempty"`
+
+	// Extensions holds the custom extension objects
+	// of a JSON Feed item.
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
 }
 
 func (i *Item) String() string {
@@ -274,17 +787,47 @@ type Enclosure struct {
 	Length uint   `json:"length"`
 }
 
-// Get uses http.Get to fetch an enclosure.
+// Get uses DefaultRequestFunc to fetch an enclosure.
 func (e *Enclosure) Get() (io.ReadCloser, error) {
+	return e.GetContext(context.Background())
+}
+
+// GetContext uses DefaultRequestFunc to fetch an
+// enclosure. The request is cancelled if ctx is
+// done before the body has been read.
+func (e *Enclosure) GetContext(ctx context.Context) (io.ReadCloser, error) {
 	if e == nil || e.URL == "" {
-		return nil, errors.New("No enclosure")
+		return nil, ErrNoEnclosure
+	}
+
+	return get(ctx, e.URL)
+}
+
+// get fetches url with DefaultRequestFunc. As
+// with feeds, a status other than 2xx gives an
+// *HTTPStatusError.
+func get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	res, err := http.Get(e.URL)
+	res, err := DefaultRequestFunc(req)
 	if err != nil {
 		return nil, err
 	}
 
+	if res.StatusCode != 0 && (res.StatusCode < 200 || res.StatusCode > 299) {
+		res.Body.Close()
+		return nil, &HTTPStatusError{
+			URL:        url,
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Header:     res.Header,
+			RetryAfter: retryAfter(res.Header, time.Now()),
+		}
+	}
+
 	return res.Body, nil
 }
 
@@ -297,18 +840,20 @@ type Image struct {
 	Width  uint32 `json:"width"`
 }
 
-// Get uses http.Get to fetch an image.
+// Get uses DefaultRequestFunc to fetch an image.
 func (i *Image) Get() (io.ReadCloser, error) {
-	if i == nil || i.URL == "" {
-		return nil, errors.New("No image")
-	}
+	return i.GetContext(context.Background())
+}
 
-	res, err := http.Get(i.URL)
-	if err != nil {
-		return nil, err
+// GetContext uses DefaultRequestFunc to fetch an
+// image. The request is cancelled if ctx is done
+// before the body has been read.
+func (i *Image) GetContext(ctx context.Context) (io.ReadCloser, error) {
+	if i == nil || i.URL == "" {
+		return nil, ErrNoImage
 	}
 
-	return res.Body, nil
+	return get(ctx, i.URL)
 }
 
 func (i *Image) String() string {
@@ -1,119 +1,80 @@
 package rss
 
 import (
-	"bytes"
 	"encoding/xml"
 	"fmt"
-	"sort"
-	"strings"
 	"time"
 )
 
-func parseRSS1(data []byte) (*Feed, error) {
-	warnings := false
-	feed := rss1_0Feed{}
-	p := xml.NewDecoder(bytes.NewReader(data))
-	p.CharsetReader = charsetReader
-	err := p.Decode(&feed)
-	if err != nil {
-		return nil, err
-	}
-	if feed.Channel == nil {
-		return nil, fmt.Errorf("no channel found in %q", string(data))
-	}
-
-	channel := feed.Channel
-
+// Feed returns the channel's metadata. It
+// does not include any items.
+func (channel *rss1_0Channel) Feed() *Feed {
 	out := new(Feed)
 	out.Title = channel.Title
 	out.Description = channel.Description
 	out.Link = channel.Link
 	out.Image = channel.Image.Image()
-	if channel.MinsToLive != 0 {
-		sort.Ints(channel.SkipHours)
-		next := time.Now().Add(time.Duration(channel.MinsToLive) * time.Minute)
-		for _, hour := range channel.SkipHours {
-			if hour == next.Hour() {
-				next = next.Add(time.Duration(60-next.Minute()) * time.Minute)
-			}
-		}
-		trying := true
-		for trying {
-			trying = false
-			for _, day := range channel.SkipDays {
-				if strings.Title(day) == next.Weekday().String() {
-					next = next.Add(time.Duration(24-next.Hour()) * time.Hour)
-					trying = true
-					break
-				}
-			}
-		}
-
-		out.Refresh = next
+	out.DublinCore = channel.dc.DublinCore()
+	if out.DublinCore != nil {
+		out.DublinCore.fillFeed(out)
 	}
-
-	if out.Refresh.IsZero() {
-		out.Refresh = time.Now().Add(DefaultRefreshInterval)
+	out.TTL = time.Duration(channel.MinsToLive) * time.Minute
+	out.SkipHours = parseSkipHours(channel.SkipHours)
+	out.SkipDays = parseSkipDays(channel.SkipDays)
+	now := time.Now()
+	if out.TTL != 0 {
+		out.Refresh = nextRefresh(now, out.TTL, out.SkipHours, out.SkipDays)
+	} else if next := channel.next(now); !next.IsZero() {
+		out.Refresh = nextRefresh(next, 0, out.SkipHours, out.SkipDays)
 	}
 
-	out.Items = make([]*Item, 0, len(feed.Items))
-	out.ItemMap = make(map[string]struct{})
-
-	// Process items.
-	for _, item := range feed.Items {
+	return out
+}
 
-		if item.ID == "" {
-			if item.Link == "" {
-				if debug {
-					fmt.Printf("[w] Item %q has no ID or link and will be ignored.\n", item.Title)
-					fmt.Printf("[w] %#v\n", item)
-				}
-				warnings = true
-				continue
+// Item converts item, returning nil if it
+// cannot be identified.
+func (item *rss1_0Item) Item() *Item {
+	if item.ID == "" {
+		if item.Link == "" {
+			if debug {
+				fmt.Printf("[w] Item %q has no ID or link and will be ignored.\n", item.Title)
+				fmt.Printf("[w] %#v\n", item)
 			}
-			item.ID = item.Link
-		}
-
-		// Skip items already known.
-		if _, ok := out.ItemMap[item.ID]; ok {
-			continue
+			return nil
 		}
+		item.ID = item.Link
+	}
 
-		next := new(Item)
-		next.Title = item.Title
-		next.Summary = item.Description
-		next.Content = item.Content
-		next.Link = item.Link
-		if item.Date != "" {
-			next.Date, err = parseTime(item.Date)
-			if err == nil {
-				next.DateValid = true
-			}
-		} else if item.PubDate != "" {
-			next.Date, err = parseTime(item.PubDate)
-			if err == nil {
-				next.DateValid = true
-			}
-		}
-		next.ID = item.ID
-		if len(item.Enclosures) > 0 {
-			next.Enclosures = make([]*Enclosure, len(item.Enclosures))
-			for i := range item.Enclosures {
-				next.Enclosures[i] = item.Enclosures[i].Enclosure()
-			}
+	var err error
+	next := new(Item)
+	next.Title = item.Title
+	next.Summary = item.Description
+	next.Content = item.Content
+	next.Link = item.Link
+	next.Media = item.media.Media()
+	if next.Media != nil {
+		next.Media.fill(next)
+	}
+	if item.PubDate != "" {
+		next.Date, err = parseTime(item.PubDate)
+		if err == nil {
+			next.DateValid = true
 		}
-		next.Read = false
-
-		out.Items = append(out.Items, next)
-		out.ItemMap[next.ID] = struct{}{}
-		out.Unread++
 	}
-
-	if warnings && debug {
-		fmt.Printf("[i] Encountered warnings:\n%s\n", data)
+	next.DublinCore = item.dc.DublinCore()
+	if next.DublinCore != nil {
+		next.DublinCore.fillItem(next)
+	}
+	next.ID = item.ID
+	if len(item.Enclosures) > 0 {
+		next.Enclosures = make([]*Enclosure, len(item.Enclosures))
+		for i := range item.Enclosures {
+			next.Enclosures[i] = item.Enclosures[i].Enclosure()
+		}
 	}
+	next.Read = false
 
-	return out, nil
+	return next
 }
 
 type rss1_0Feed struct {
@@ -131,6 +92,27 @@ type rss1_0Channel struct {
 	MinsToLive  int         `xml:"ttl"`
 	SkipHours   []int       `xml:"skipHours>hour"`
 	SkipDays    []string    `xml:"skipDays>day"`
+	syndication
+
+	dc dublinCoreElements
+}
+
+// UnmarshalXML implements xml.Unmarshaler, so
+// that the channel's elements in extension
+// namespaces are decoded by namespace, as an RSS
+// 1.0 channel is nested in the document.
+func (channel *rss1_0Channel) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain rss1_0Channel
+	return decodeElement(d, &start, (*plain)(channel), channel.extensions())
+}
+
+// extensions returns the values into which the
+// channel's elements in extension namespaces
+// are decoded.
+func (channel *rss1_0Channel) extensions() map[string]interface{} {
+	ext := make(map[string]interface{})
+	channel.dc.register(ext)
+	return ext
 }
 
 type rss1_0Item struct {
@@ -140,10 +122,22 @@ type rss1_0Item struct {
 	Content     string   `xml:"encoded"`
 	Link        string   `xml:"link"`
 	PubDate     string   `xml:"pubDate"`
-	Date        string   `xml:"date"`
 	DateValid   bool
 	ID          string            `xml:"guid"`
 	Enclosures  []rss1_0Enclosure `xml:"enclosure"`
+
+	media mediaElements
+	dc    dublinCoreElements
+}
+
+// extensions returns the values into which the
+// item's elements in extension namespaces are
+// decoded.
+func (item *rss1_0Item) extensions() map[string]interface{} {
+	ext := make(map[string]interface{})
+	item.media.register(ext)
+	item.dc.register(ext)
+	return ext
 }
 
 type rss1_0Enclosure struct {
@@ -1,33 +1,22 @@
 package rss
 
 import (
-	"bytes"
 	"encoding/xml"
 	"fmt"
-	"sort"
 	"strings"
 	"time"
 )
 
-func parseRSS2(data []byte) (*Feed, error) {
-	warnings := false
-	feed := rss2_0Feed{}
-	p := xml.NewDecoder(bytes.NewReader(data))
-	p.CharsetReader = charsetReader
-	err := p.Decode(&feed)
-	if err != nil {
-		return nil, err
-	}
-	if feed.Channel == nil {
-		return nil, fmt.Errorf("no channel found in %q", string(data))
-	}
-
-	channel := feed.Channel
-
+// Feed returns the channel's metadata. It
+// does not include any items.
+func (channel *rss2_0Channel) Feed() *Feed {
 	out := new(Feed)
 	out.Title = channel.Title
 	out.Language = channel.Language
 	out.Author = channel.Author
+	if out.Author == "" {
+		out.Author = channel.ManagingEditor
+	}
 	out.Description = channel.Description
 	out.Categories = channel.Categories.toArray()
 	for _, link := range channel.Link {
@@ -37,93 +26,96 @@ func parseRSS2(data []byte) (*Feed, error) {
 		}
 	}
 	out.Image = channel.Image.Image()
-	if channel.MinsToLive != 0 {
-		sort.Ints(channel.SkipHours)
-		next := time.Now().Add(time.Duration(channel.MinsToLive) * time.Minute)
-		for _, hour := range channel.SkipHours {
-			if hour == next.Hour() {
-				next = next.Add(time.Duration(60-next.Minute()) * time.Minute)
-			}
+	out.DublinCore = channel.dc.DublinCore()
+	if out.DublinCore != nil {
+		out.DublinCore.fillFeed(out)
+	}
+	out.Podcast = channel.itunes.Podcast()
+	if p := out.Podcast; p != nil {
+		if out.Author == "" {
+			out.Author = p.Author
 		}
-		trying := true
-		for trying {
-			trying = false
-			for _, day := range channel.SkipDays {
-				if strings.Title(day) == next.Weekday().String() {
-					next = next.Add(time.Duration(24-next.Hour()) * time.Hour)
-					trying = true
-					break
-				}
-			}
+		if len(out.Categories) == 0 {
+			out.Categories = p.categoryNames()
+		}
+		if out.Image.URL == "" && out.Image.Href == "" {
+			out.Image.Href = p.Image
 		}
-
-		out.Refresh = next
 	}
-
-	if out.Refresh.IsZero() {
-		out.Refresh = time.Now().Add(DefaultRefreshInterval)
+	out.PodcastIndex = channel.podcastIndex.PodcastIndex()
+	out.TTL = time.Duration(channel.MinsToLive) * time.Minute
+	out.SkipHours = parseSkipHours(channel.SkipHours)
+	out.SkipDays = parseSkipDays(channel.SkipDays)
+	now := time.Now()
+	if out.TTL != 0 {
+		out.Refresh = nextRefresh(now, out.TTL, out.SkipHours, out.SkipDays)
+	} else if next := channel.next(now); !next.IsZero() {
+		out.Refresh = nextRefresh(next, 0, out.SkipHours, out.SkipDays)
 	}
 
-	out.Items = make([]*Item, 0, len(channel.Items))
-	out.ItemMap = make(map[string]struct{})
-
-	// Process items.
-	for _, item := range channel.Items {
+	return out
+}
 
-		if item.ID == "" {
-			if item.Link == "" {
-				if debug {
-					fmt.Printf("[w] Item %q has no ID or link and will be ignored.\n", item.Title)
-					fmt.Printf("[w] %#v\n", item)
-				}
-				warnings = true
-				continue
+// Item converts item, returning nil if it
+// cannot be identified.
+func (item *rss2_0Item) Item() *Item {
+	if item.ID == "" {
+		if item.Link == "" {
+			if debug {
+				fmt.Printf("[w] Item %q has no ID or link and will be ignored.\n", item.Title)
+				fmt.Printf("[w] %#v\n", item)
 			}
-			item.ID = item.Link
+			return nil
 		}
+		item.ID = item.Link
+	}
 
-		// Skip items already known.
-		if _, ok := out.ItemMap[item.ID]; ok {
-			continue
+	var err error
+	next := new(Item)
+	next.Title = item.Title
+	next.Summary = item.Description
+	next.Content = item.Content
+	next.Categories = item.Categories
+	next.Link = item.Link
+	next.Author = item.Author
+	next.Image = item.Image.Image()
+	next.Podcast = item.itunes.Podcast()
+	if p := next.Podcast; p != nil {
+		if next.Title == "" {
+			next.Title = p.Title
 		}
-
-		next := new(Item)
-		next.Title = item.Title
-		next.Summary = item.Description
-		next.Content = item.Content
-		next.Categories = item.Categories
-		next.Link = item.Link
-		next.Image = item.Image.Image()
-		if item.Date != "" {
-			next.Date, err = parseTime(item.Date)
-			if err == nil {
-				next.DateValid = true
-			}
-		} else if item.PubDate != "" {
-			next.Date, err = parseTime(item.PubDate)
-			if err == nil {
-				next.DateValid = true
-			}
+		if next.Author == "" {
+			next.Author = p.Author
 		}
-		next.ID = item.ID
-		if len(item.Enclosures) > 0 {
-			next.Enclosures = make([]*Enclosure, len(item.Enclosures))
-			for i := range item.Enclosures {
-				next.Enclosures[i] = item.Enclosures[i].Enclosure()
-			}
+		if next.Image.URL == "" && next.Image.Href == "" {
+			next.Image.Href = p.Image
 		}
-		next.Read = false
-
-		out.Items = append(out.Items, next)
-		out.ItemMap[next.ID] = struct{}{}
-		out.Unread++
 	}
-
-	if warnings && debug {
-		fmt.Printf("[i] Encountered warnings:\n%s\n", data)
+	next.PodcastIndex = item.podcastIndex.PodcastIndex()
+	next.Media = item.media.Media()
+	if next.Media != nil {
+		next.Media.fill(next)
 	}
+	if item.PubDate != "" {
+		next.Date, err = parseTime(item.PubDate)
+		if err == nil {
+			next.DateValid = true
+		}
+	}
+	next.DublinCore = item.dc.DublinCore()
+	if next.DublinCore != nil {
+		next.DublinCore.fillItem(next)
+	}
+	next.ID = item.ID
+	if len(item.Enclosures) > 0 {
+		next.Enclosures = make([]*Enclosure, len(item.Enclosures))
+		for i := range item.Enclosures {
+			next.Enclosures[i] = item.Enclosures[i].Enclosure()
+		}
+	}
+	next.Read = false
 
-	return out, nil
+	return next
 }
 
 type rss2_0Feed struct {
@@ -132,8 +124,9 @@ type rss2_0Feed struct {
 }
 
 type rss2_0Category struct {
-	XMLName xml.Name `xml:"category"`
-	Name    string   `xml:"text,attr"`
+	XMLName  xml.Name `xml:"category"`
+	Name     string   `xml:"text,attr"`
+	Chardata string   `xml:",chardata"`
 }
 
 type rss2_0CategorySlice []rss2_0Category
@@ -146,23 +139,80 @@ func (r rss2_0CategorySlice) toArray() (result []string) {
 	result = make([]string, count)
 	for i, _ := range r {
 		result[i] = r[i].Name
+		if result[i] == "" {
+			result[i] = strings.TrimSpace(r[i].Chardata)
+		}
 	}
 	return
 }
 
 type rss2_0Channel struct {
-	XMLName     xml.Name            `xml:"channel"`
-	Title       string              `xml:"title"`
-	Language    string              `xml:"language"`
-	Author      string              `xml:"author"`
-	Description string              `xml:"description"`
-	Link        []rss2_0Link        `xml:"link"`
-	Image       rss2_0Image         `xml:"image"`
-	Categories  rss2_0CategorySlice `xml:"category"`
-	Items       []rss2_0Item        `xml:"item"`
-	MinsToLive  int                 `xml:"ttl"`
-	SkipHours   []int               `xml:"skipHours>hour"`
-	SkipDays    []string            `xml:"skipDays>day"`
+	XMLName        xml.Name            `xml:"channel"`
+	Title          string              `xml:"title"`
+	Language       string              `xml:"language"`
+	Author         string              `xml:"author"`
+	ManagingEditor string              `xml:"managingEditor"`
+	Description    string              `xml:"description"`
+	Link           []rss2_0Link        `xml:"link"`
+	Image          rss2_0Image         `xml:"image"`
+	Categories     rss2_0CategorySlice `xml:"category"`
+	Items          []rss2_0Item        `xml:"item"`
+	MinsToLive     int                 `xml:"ttl"`
+	SkipHours      []int               `xml:"skipHours>hour"`
+	SkipDays       []string            `xml:"skipDays>day"`
+	syndication
+
+	itunes       itunesElements
+	podcastIndex podcastIndexElements
+	dc           dublinCoreElements
+}
+
+// nsContent is the namespace of the RSS 1.0
+// Content module, whose content:encoded holds the
+// full content of RSS 2.0 items.
+const nsContent = "http://purl.org/rss/1.0/modules/content/"
+
+// isCore implements coreElements. RSS 2.0 has no
+// namespace, so only elements with none are taken,
+// along with those of other namespaces that the
+// channel uses, such as atom:link.
+func (channel *rss2_0Channel) isCore(name xml.Name) bool {
+	return isRSS2Core(name)
+}
+
+// isCore implements coreElements, like the
+// channel's.
+func (item *rss2_0Item) isCore(name xml.Name) bool {
+	return isRSS2Core(name)
+}
+
+// isRSS2Core reports whether the element with the
+// given name is one of the standard elements of an
+// RSS 2.0 channel or item. Prefixes that a feed
+// uses without declaring them are accepted too.
+func isRSS2Core(name xml.Name) bool {
+	switch name.Space {
+	case "":
+		return true
+	case nsContent, "content":
+		return name.Local == "encoded"
+	case nsAtom, "atom":
+		return name.Local == "link"
+	case nsSyndication:
+		return true
+	}
+	return false
+}
+
+// extensions returns the values into which the
+// channel's elements in extension namespaces
+// are decoded.
+func (channel *rss2_0Channel) extensions() map[string]interface{} {
+	ext := make(map[string]interface{})
+	channel.itunes.register(ext)
+	channel.podcastIndex.register(ext)
+	channel.dc.register(ext)
+	return ext
 }
 
 type rss2_0Link struct {
@@ -181,12 +231,29 @@ type rss2_0Item struct {
 	Content     string           `xml:"encoded"`
 	Categories  rss2_0Categories `xml:"category"`
 	Link        string           `xml:"link"`
+	Author      string           `xml:"author"`
 	PubDate     string           `xml:"pubDate"`
-	Date        string           `xml:"date"`
 	Image       rss2_0Image      `xml:"image"`
 	DateValid   bool
 	ID          string            `xml:"guid"`
 	Enclosures  []rss2_0Enclosure `xml:"enclosure"`
+
+	itunes       itunesElements
+	podcastIndex podcastIndexElements
+	media        mediaElements
+	dc           dublinCoreElements
+}
+
+// extensions returns the values into which the
+// item's elements in extension namespaces are
+// decoded.
+func (item *rss2_0Item) extensions() map[string]interface{} {
+	ext := make(map[string]interface{})
+	item.itunes.register(ext)
+	item.podcastIndex.register(ext)
+	item.media.register(ext)
+	item.dc.register(ext)
+	return ext
 }
 
 type rss2_0Enclosure struct {
@@ -1,14 +1,21 @@
 package rss
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestParseTitle(t *testing.T) {
@@ -104,6 +111,7 @@ func TestFeedUnmarshalUpdate(t *testing.T) {
 	var unmarshalledFeed Feed
 	err = json.Unmarshal(jsonBlob, &unmarshalledFeed)
 
+	defer func(fetchFunc FetchFunc) { DefaultFetchFunc = fetchFunc }(DefaultFetchFunc)
 	var defaultFetchFuncCalled = 0
 	DefaultFetchFunc = func(url string) (resp *http.Response, err error) {
 		defaultFetchFuncCalled++
@@ -129,6 +137,23 @@ func TestFeedUnmarshalUpdate(t *testing.T) {
 	}
 }
 
+func TestFetchDefaultFetchFunc(t *testing.T) {
+	defer func(fetchFunc FetchFunc) { DefaultFetchFunc = fetchFunc }(DefaultFetchFunc)
+	called := 0
+	DefaultFetchFunc = func(url string) (*http.Response, error) {
+		called++
+		return MakeTestdataFetchFunc("rssupdate-1")(url)
+	}
+
+	feed, err := Fetch("http://localhost/dummyrss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if called != 1 || feed.FetchFunc == nil {
+		t.Errorf("Fetch did not use the replaced DefaultFetchFunc")
+	}
+}
+
 func TestItemGUIDs(t *testing.T) {
 	feed1, err := FetchByFunc(MakeTestdataFetchFunc("rss_2.0"), "http://localhost/dummyfeed1")
 	if err != nil {
@@ -158,3 +183,567 @@ func TestItemGUIDs(t *testing.T) {
 		t.Errorf("Expected two items in feed 'rssupdate' after step 2, got %v", len(feed2.Items))
 	}
 }
+
+func TestConditionalUpdate(t *testing.T) {
+	const etag = `"abc123"`
+	const lastModified = "Mon, 06 Sep 2010 00:01:00 GMT"
+	requests, downloads := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		data, _ := ioutil.ReadFile("testdata/rssupdate-1")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	feed, err := FetchByClient(server.URL, server.Client())
+	if err != nil {
+		t.Fatalf("Failed fetching testdata 'rssupdate-1': %v", err)
+	}
+
+	assertEqual(etag, feed.ETag, t)
+	assertEqual(lastModified, feed.LastModified, t)
+
+	feed.Items[0].Read = true
+	feed.Unread--
+
+	err = feed.Update()
+	if err != nil {
+		t.Fatalf("Failed updating unmodified feed: %v", err)
+	}
+
+	if requests != 2 || downloads != 1 {
+		t.Errorf("Expected 2 requests and 1 download, got %d and %d", requests, downloads)
+	}
+
+	if len(feed.Items) != 1 || !feed.Items[0].Read || feed.Unread != 0 {
+		t.Errorf("Expected unmodified feed to be left alone, got %d items, %d unread", len(feed.Items), feed.Unread)
+	}
+
+	if !feed.Refresh.After(time.Now()) {
+		t.Errorf("Expected refresh to be pushed back after 304, got %v", feed.Refresh)
+	}
+}
+
+func TestConditionalUpdateFromJSON(t *testing.T) {
+	const etag = `"abc123"`
+	requests, downloads := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		data, _ := ioutil.ReadFile("testdata/rssupdate-1")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	feed, err := FetchByClient(server.URL, server.Client())
+	if err != nil {
+		t.Fatalf("Failed fetching testdata 'rssupdate-1': %v", err)
+	}
+
+	// A feed restored from JSON has no RequestFunc,
+	// but should still make conditional requests.
+	jsonBlob, err := json.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Feed
+	if err := json.Unmarshal(jsonBlob, &restored); err != nil {
+		t.Fatal(err)
+	}
+	restored.Refresh = time.Time{}
+
+	if err := restored.Update(); err != nil {
+		t.Fatalf("Failed updating unmodified feed: %v", err)
+	}
+	if requests != 2 || downloads != 1 {
+		t.Errorf("Expected 2 requests and 1 download, got %d and %d", requests, downloads)
+	}
+	if !restored.Refresh.After(time.Now()) {
+		t.Errorf("Expected refresh to be pushed back after 304, got %v", restored.Refresh)
+	}
+}
+
+func TestFetchContext(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := FetchContext(ctx, server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded fetching feed, got %v", err)
+	}
+
+	feed := &Feed{UpdateURL: server.URL, RequestFunc: DefaultRequestFunc}
+	err = feed.UpdateContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded updating feed, got %v", err)
+	}
+
+	_, err = (&Enclosure{URL: server.URL}).GetContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded fetching enclosure, got %v", err)
+	}
+}
+
+func TestUpdateContextFromJSON(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	// A feed restored from JSON has no RequestFunc,
+	// but its updates can still be cancelled.
+	var feed Feed
+	jsonBlob := fmt.Sprintf(`{"updateurl": %q}`, server.URL)
+	if err := json.Unmarshal([]byte(jsonBlob), &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := feed.UpdateContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled update, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Update took %v to be cancelled", elapsed)
+	}
+}
+
+func TestUpdateContextFetchFunc(t *testing.T) {
+	called := false
+	feed := &Feed{
+		UpdateURL: "http://localhost/dummyrss",
+		FetchFunc: func(url string) (*http.Response, error) {
+			called = true
+			return MakeTestdataFetchFunc("rssupdate-1")(url)
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := feed.UpdateContext(ctx); err != context.Canceled {
+		t.Errorf("Expected context canceled, got %v", err)
+	}
+
+	if called {
+		t.Error("FetchFunc was called after the context was cancelled")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := map[string]Format{
+		"atom_1.0":                FormatAtom,
+		"atom_1.0-1":              FormatAtom,
+		"atom_1.0_enclosure":      FormatAtom,
+		"atom_1.0_html":           FormatAtom,
+		"atom_1.0_media":          FormatAtom,
+		"atom_1.0_podcast":        FormatAtom,
+		"atom_1.0_syndication":    FormatAtom,
+		"json_feed_1.0":           FormatJSON,
+		"json_feed_1.1":           FormatJSON,
+		"rss_0.91":                FormatRSS2,
+		"rss_0.92":                FormatRSS2,
+		"rss_1.0":                 FormatRSS2, // Despite its name.
+		"rss_1.0_enclosure":       FormatRSS1,
+		"rss_1.0_syndication":     FormatRSS1,
+		"rss_2.0":                 FormatRSS2,
+		"rss_2.0-1":               FormatRSS2,
+		"rss_2.0-1_enclosure":     FormatRSS2,
+		"rss_2.0_content_encoded": FormatRSS2,
+		"rss_2.0_enclosure":       FormatRSS2,
+		"rss_2.0_media":           FormatRSS2,
+		"rss_2.0_googleplay":      FormatRSS2,
+		"rss_2.0_podcast":         FormatRSS2,
+		"rss_2.0_podcastindex":    FormatRSS2,
+		"rss_2.0_syndication":     FormatRSS2,
+		"rssupdate-1":             FormatRSS2,
+		"rssupdate-2":             FormatRSS2,
+	}
+
+	if len(tests) != len(testdataFeeds) {
+		t.Errorf("Expected a format for each of the %d feeds in testdata, got %d", len(testdataFeeds), len(tests))
+	}
+
+	for test, want := range tests {
+		name := filepath.Join("testdata", test)
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Reading %s: %v", name, err)
+		}
+
+		feed, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Parsing %s: %v", name, err)
+		}
+
+		if feed.Format != want {
+			t.Errorf("%s: got %q, want %q", name, feed.Format, want)
+		}
+	}
+}
+
+func TestParseFormatTricky(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Format
+	}{{
+		name: "Atom mentioning <rss",
+		data: `<feed xmlns="http://www.w3.org/2005/Atom"><title>About &lt;rss&gt;</title>
+			<entry><id>1</id><content type="html"><![CDATA[Use <rss version="2.0"> for RSS.]]></content></entry></feed>`,
+		want: FormatAtom,
+	}, {
+		name: "RSS 1.0 with single quotes",
+		data: `<rdf:RDF xmlns:rdf='http://www.w3.org/1999/02/22-rdf-syntax-ns#' xmlns='http://purl.org/rss/1.0/'>
+			<channel><title>Quoted</title></channel><item><link>http://example.com/</link></item></rdf:RDF>`,
+		want: FormatRSS1,
+	}, {
+		name: "RSS 1.0 with prefixed namespace",
+		data: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:rss="http://purl.org/rss/1.0/">
+			<rss:channel><rss:title>Prefixed</rss:title></rss:channel>
+			<rss:item><rss:link>http://example.com/</rss:link></rss:item></rdf:RDF>`,
+		want: FormatRSS1,
+	}, {
+		name: "byte order mark, prolog and comments",
+		data: "\xef\xbb\xbf<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+			`<?xml-stylesheet type="text/xsl" href="feed.xsl"?>
+			<!-- <rss version="2.0"> -->
+			<!DOCTYPE feed>
+			<feed xmlns="http://www.w3.org/2005/Atom"><title>Prolog</title></feed>`,
+		want: FormatAtom,
+	}, {
+		name: "RSS mentioning Atom",
+		data: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+			<atom:link href="http://example.com/feed" rel="self"/><title>&lt;feed&gt;</title></channel></rss>`,
+		want: FormatRSS2,
+	}, {
+		name: "JSON Feed with byte order mark and space",
+		data: "\xef\xbb\xbf\n\t{\"version\": \"https://jsonfeed.org/version/1.1\", \"items\": []}",
+		want: FormatJSON,
+	}}
+
+	for _, tt := range tests {
+		feed, err := Parse([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if feed.Format != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, feed.Format, tt.want)
+		}
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	tests := map[string]string{
+		"HTML":               "<!DOCTYPE html><html><head><title>Not a feed</title></head></html>",
+		"RDF without RSS":    `<RDF xmlns="http://example.com/not-rdf"></RDF>`,
+		"Atom-like document": `<feed xmlns="http://example.com/not-atom"></feed>`,
+	}
+
+	for name, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error, got none", name)
+		}
+	}
+}
+
+func TestFetchStatusErrors(t *testing.T) {
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(status)
+		w.Write([]byte("<html><body>Error</body></html>"))
+	}))
+	defer server.Close()
+
+	for _, code := range []int{http.StatusNotFound, http.StatusGone, http.StatusInternalServerError} {
+		status = code
+		_, err := FetchByClient(server.URL, server.Client())
+
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) {
+			t.Errorf("%d: got %v, want *HTTPStatusError", code, err)
+			continue
+		}
+		if statusErr.StatusCode != code {
+			t.Errorf("%d: got status %d", code, statusErr.StatusCode)
+		}
+		assertEqual("text/html", statusErr.Header.Get("Content-Type"), t)
+		if gone := errors.Is(err, ErrGone); gone != (code == http.StatusGone) {
+			t.Errorf("%d: errors.Is(err, ErrGone) = %v", code, gone)
+		}
+	}
+
+	// FetchFuncs get the same checks.
+	_, err := FetchByFunc(func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusForbidden,
+			Body:       ioutil.NopCloser(strings.NewReader("Forbidden")),
+		}, nil
+	}, "http://example.com/feed")
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("FetchByFunc: got %v, want 403 *HTTPStatusError", err)
+	}
+}
+
+func TestUpdateRetryAfter(t *testing.T) {
+	retryAt := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	headers := map[int]string{
+		http.StatusTooManyRequests:    "3600",
+		http.StatusServiceUnavailable: retryAt.Format(http.TimeFormat),
+	}
+	wants := map[int]time.Time{
+		http.StatusTooManyRequests:    time.Now().Add(time.Hour),
+		http.StatusServiceUnavailable: retryAt,
+	}
+
+	for code, header := range headers {
+		fail := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fail {
+				w.Header().Set("Retry-After", header)
+				w.WriteHeader(code)
+				return
+			}
+			data, _ := ioutil.ReadFile("testdata/rssupdate-1")
+			w.Write(data)
+		}))
+
+		feed, err := FetchByClient(server.URL, server.Client())
+		if err != nil {
+			t.Fatalf("%d: fetching: %v", code, err)
+		}
+
+		fail = true
+		err = feed.Update()
+		server.Close()
+
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != code {
+			t.Errorf("%d: got %v, want *HTTPStatusError", code, err)
+			continue
+		}
+		if !statusErr.Temporary() {
+			t.Errorf("%d: expected a temporary error", code)
+		}
+		if diff := feed.Refresh.Sub(wants[code]); diff < -time.Minute || diff > time.Minute {
+			t.Errorf("%d: got refresh %v, want about %v", code, feed.Refresh, wants[code])
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"":                              {},
+		"120":                           now.Add(2 * time.Minute),
+		"-5":                            {},
+		"soon":                          {},
+		"Wed, 01 Jan 2020 13:00:00 GMT": now.Add(time.Hour),
+	}
+
+	for value, want := range tests {
+		h := http.Header{}
+		if value != "" {
+			h.Set("Retry-After", value)
+		}
+		if got := retryAfter(h, now); !got.Equal(want) {
+			t.Errorf("%q: got %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestUpdateRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadFile("testdata/rssupdate-1")
+		w.Write(data)
+	})
+	mux.Handle("/moved", http.RedirectHandler("/moved-again", http.StatusMovedPermanently))
+	mux.Handle("/moved-again", http.RedirectHandler("/feed", http.StatusPermanentRedirect))
+	mux.Handle("/temporary", http.RedirectHandler("/feed", http.StatusFound))
+	mux.Handle("/mixed", http.RedirectHandler("/temporary", http.StatusMovedPermanently))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := map[string]bool{
+		"/moved":     true,
+		"/temporary": false,
+		"/mixed":     false,
+	}
+
+	for path, permanent := range tests {
+		oldURL := server.URL + path
+		feed := &Feed{UpdateURL: oldURL}
+		var moved []string
+		feed.Moved = func(f *Feed, oldURL string) {
+			moved = append(moved, oldURL, f.UpdateURL)
+		}
+
+		err := feed.UpdateByFunc(func(url string) (*http.Response, error) {
+			return server.Client().Get(url)
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+
+		if feed.Redirect == nil {
+			t.Fatalf("%s: redirect was not recorded", path)
+		}
+		assertEqual(server.URL+"/feed", feed.Redirect.URL, t)
+		if feed.Redirect.Permanent != permanent {
+			t.Errorf("%s: got permanent %v, want %v", path, feed.Redirect.Permanent, permanent)
+		}
+
+		if permanent {
+			assertEqual(server.URL+"/feed", feed.UpdateURL, t)
+			if !reflect.DeepEqual(moved, []string{oldURL, server.URL + "/feed"}) {
+				t.Errorf("%s: got moved events %q", path, moved)
+			}
+		} else {
+			assertEqual(oldURL, feed.UpdateURL, t)
+			if moved != nil {
+				t.Errorf("%s: unexpected moved events %q", path, moved)
+			}
+		}
+	}
+
+	feed, err := FetchByClient(server.URL+"/feed", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Redirect != nil {
+		t.Errorf("got redirect %+v without redirects", feed.Redirect)
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	feed, err := Parse([]byte(`<rss version="2.0"><channel>
+		<item><guid>1</guid></item>
+		<item><guid>2</guid></item>
+		<item><guid>3</guid></item>
+	</channel></rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !feed.MarkRead("2") || !feed.MarkRead("2") {
+		t.Errorf("MarkRead did not find item 2")
+	}
+	if feed.MarkRead("4") {
+		t.Errorf("MarkRead found a missing item")
+	}
+	if feed.Unread != 2 {
+		t.Errorf("got %d unread, want 2", feed.Unread)
+	}
+
+	var ids []string
+	for _, item := range feed.UnreadItems() {
+		ids = append(ids, item.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"1", "3"}) {
+		t.Errorf("got unread items %q, want 1 and 3", ids)
+	}
+
+	feed.MarkAllRead()
+	if feed.Unread != 0 || len(feed.UnreadItems()) != 0 {
+		t.Errorf("got %d unread after MarkAllRead", feed.Unread)
+	}
+}
+
+// alwaysRefresh is a RefreshPolicy that lets a
+// feed be updated again straight away.
+type alwaysRefresh struct{}
+
+func (alwaysRefresh) NextRefresh(f *Feed, now, hint time.Time, err error) time.Time {
+	return time.Time{}
+}
+
+func TestConcurrentFeed(t *testing.T) {
+	var mu sync.Mutex
+	n := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n++
+		id := n
+		mu.Unlock()
+		fmt.Fprintf(w, `<rss version="2.0"><channel><title>Feed %d</title><item><guid>%d</guid></item></channel></rss>`, id, id)
+	}))
+	defer server.Close()
+
+	feed := &Feed{
+		UpdateURL:     server.URL,
+		RequestFunc:   server.Client().Do,
+		RefreshPolicy: alwaysRefresh{},
+	}
+
+	const updates = 50
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < updates/2; j++ {
+				if err := feed.Update(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= updates; i++ {
+			feed.MarkRead(strconv.Itoa(i))
+			feed.UnreadItems()
+			_ = feed.String()
+			feed.WriteJSONFeed(ioutil.Discard)
+			if i%10 == 0 {
+				feed.MarkAllRead()
+			}
+		}
+	}()
+
+	wg.Wait()
+	<-done
+
+	unread := uint32(len(feed.UnreadItems()))
+	if len(feed.Items) != updates || feed.Unread != unread {
+		t.Errorf("got %d items and %d unread (%d counted), want %d items", len(feed.Items), feed.Unread, unread, updates)
+	}
+}
@@ -0,0 +1,185 @@
+package rss
+
+import "fmt"
+
+// A Store keeps feeds and the state of their items,
+// such as which have been read, so that they survive
+// restarts. Feeds are keyed by their UpdateURL.
+//
+// A Feed with a Store saves its changes to it as it
+// is updated, pruned, or marked as read. The Feed is
+// locked while the Store is called, so the Store may
+// read its fields but must not call its methods.
+type Store interface {
+	// FeedURLs returns the URLs of the stored feeds.
+	FeedURLs() ([]string, error)
+
+	// LoadFeed returns the stored feed with the given
+	// URL, with its items, or ErrNotStored.
+	LoadFeed(url string) (*Feed, error)
+
+	// SaveFeed stores everything about f except its
+	// items, replacing what was stored before.
+	SaveFeed(f *Feed) error
+
+	// SaveItems adds items to the feed with the given
+	// URL, replacing any with the same ID. Their Read
+	// and Starred state is saved too.
+	SaveItems(url string, items []*Item) error
+
+	// DeleteItems removes the items with the given IDs
+	// from the feed with the given URL.
+	DeleteItems(url string, ids []string) error
+
+	// SetRead sets the Read state of the items with the
+	// given IDs in the feed with the given URL.
+	SetRead(url string, ids []string, read bool) error
+
+	// SeenIDs returns the IDs of the stored items in the
+	// feed with the given URL, along with the IDs of the
+	// pruned items that it remembers (see Feed.Seen).
+	// LoadFeeds uses them to restore Feed.Seen, so the
+	// feed returned by LoadFeed need not include it.
+	SeenIDs(url string) ([]string, error)
+
+	// DeleteFeed removes the feed with the given URL
+	// and its items. It is not an error if there is no
+	// such feed.
+	DeleteFeed(url string) error
+}
+
+// LoadFeeds returns every feed in s, set to save
+// their changes to s.
+func LoadFeeds(s Store) ([]*Feed, error) {
+	urls, err := s.FeedURLs()
+	if err != nil {
+		return nil, err
+	}
+
+	feeds := make([]*Feed, 0, len(urls))
+	for _, url := range urls {
+		f, err := s.LoadFeed(url)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", url, err)
+		}
+		seen, err := s.SeenIDs(url)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", url, err)
+		}
+		f.restoreSeen(seen)
+		f.Store = s
+		feeds = append(feeds, f)
+	}
+
+	return feeds, nil
+}
+
+// restoreSeen sets f.Seen to the IDs in seen that
+// are not those of f's items, keeping their order.
+func (f *Feed) restoreSeen(seen []string) {
+	items := make(map[string]bool, len(f.Items))
+	for _, item := range f.Items {
+		items[item.ID] = true
+	}
+
+	f.Seen = nil
+	for _, id := range seen {
+		if !items[id] {
+			f.Seen = append(f.Seen, id)
+		}
+	}
+}
+
+// Save stores all of f, including its items, in
+// f.Store.
+func (f *Feed) Save() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Store == nil {
+		return ErrNoStore
+	}
+	return f.save()
+}
+
+// save is like Save, but must be called with f.mu
+// held and f.Store set. Remembered pruned items are
+// deleted again, in case an earlier save failed.
+func (f *Feed) save() error {
+	err := f.Store.SaveFeed(f)
+	if err == nil {
+		err = f.Store.SaveItems(f.UpdateURL, f.Items)
+	}
+	if err == nil && len(f.Seen) > 0 {
+		err = f.Store.DeleteItems(f.UpdateURL, f.Seen)
+	}
+
+	f.unsaved = err != nil
+	return err
+}
+
+// saveChanges stores the changes made by an update
+// in f.Store. It must be called with f.mu held.
+// oldURL is f's URL before the update, under which
+// it was stored.
+func (f *Feed) saveChanges(changes *Changes, oldURL string) error {
+	if f.unsaved || f.UpdateURL != oldURL {
+		err := f.save()
+		if err == nil && f.UpdateURL != oldURL {
+			err = f.Store.DeleteFeed(oldURL)
+		}
+		return err
+	}
+
+	err := f.Store.SaveFeed(f)
+	if changes != nil {
+		var items []*Item
+		if n := len(changes.Added) + len(changes.Modified); n > 0 {
+			ids := make(map[string]bool, n)
+			for _, id := range changes.Added {
+				ids[id] = true
+			}
+			for _, id := range changes.Modified {
+				ids[id] = true
+			}
+			for _, item := range f.Items {
+				if ids[item.ID] {
+					items = append(items, item)
+				}
+			}
+		}
+		if err == nil && len(items) > 0 {
+			err = f.Store.SaveItems(f.UpdateURL, items)
+		}
+		if err == nil && len(changes.Pruned) > 0 {
+			err = f.Store.DeleteItems(f.UpdateURL, changes.Pruned)
+		}
+	}
+
+	f.unsaved = err != nil
+	return err
+}
+
+// saveRead stores the Read state of the items with
+// the given IDs, if f has a Store. It must be called
+// with f.mu held. If it fails, all of f is saved by
+// its next update.
+func (f *Feed) saveRead(ids []string, read bool) {
+	if f.Store == nil || len(ids) == 0 {
+		return
+	}
+	if err := f.Store.SetRead(f.UpdateURL, ids, read); err != nil {
+		f.unsaved = true
+	}
+}
+
+// saveItems is like saveRead, but stores the
+// whole of each item.
+func (f *Feed) saveItems(items []*Item) {
+	if f.Store == nil || len(items) == 0 {
+		return
+	}
+	if err := f.Store.SaveItems(f.UpdateURL, items); err != nil {
+		f.unsaved = true
+	}
+}
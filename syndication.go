@@ -0,0 +1,105 @@
+package rss
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nsSyndication is the namespace of the RDF Site
+// Summary Syndication module.
+const nsSyndication = "http://purl.org/rss/1.0/modules/syndication/"
+
+// syndication holds the elements of the RDF Site
+// Summary Syndication module, which say how often
+// a feed is updated. It is used by RSS 1.0 feeds,
+// but also appears in RSS 2.0 and Atom feeds.
+//
+// See http://web.resource.org/rss/1.0/modules/syndication/
+type syndication struct {
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	UpdateBase      string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateBase"`
+}
+
+// interval returns the time between updates, or
+// zero if the feed does not give one.
+func (s *syndication) interval() time.Duration {
+	period := strings.ToLower(strings.TrimSpace(s.UpdatePeriod))
+	frequency := strings.TrimSpace(s.UpdateFrequency)
+	if period == "" && frequency == "" {
+		return 0
+	}
+
+	var d time.Duration
+	switch period {
+	case "hourly":
+		d = time.Hour
+	case "", "daily":
+		d = 24 * time.Hour
+	case "weekly":
+		d = 7 * 24 * time.Hour
+	case "monthly":
+		d = 30 * 24 * time.Hour
+	case "yearly":
+		d = 365 * 24 * time.Hour
+	default:
+		if debug {
+			fmt.Printf("[w] Unknown sy:updatePeriod %q\n", s.UpdatePeriod)
+		}
+		return 0
+	}
+
+	// The frequency is the number of updates in
+	// each period, and defaults to 1.
+	if n, err := strconv.Atoi(frequency); err == nil && n > 0 {
+		d /= time.Duration(n)
+	}
+
+	return d
+}
+
+// next returns the first scheduled update after
+// now, or the zero time if there is no schedule.
+// Updates happen every interval, starting from
+// sy:updateBase if it is given.
+func (s *syndication) next(now time.Time) time.Time {
+	interval := s.interval()
+	if interval <= 0 {
+		return time.Time{}
+	}
+
+	base, ok := parseW3CDTF(s.UpdateBase)
+	if !ok {
+		return now.Add(interval)
+	}
+	if base.After(now) {
+		return base
+	}
+
+	n := now.Sub(base)/interval + 1
+	return base.Add(n * interval)
+}
+
+// w3cdtfLayouts are the forms of the W3C date and
+// time format used by sy:updateBase and dc:date,
+// after RFC 3339, which parseTime already handles.
+var w3cdtfLayouts = []string{
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+func parseW3CDTF(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range w3cdtfLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	t, err := parseTime(s)
+	return t, err == nil
+}
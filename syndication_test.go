@@ -0,0 +1,68 @@
+package rss
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestSyndicationRefresh(t *testing.T) {
+	tests := map[string]time.Duration{
+		"rss_1.0_syndication":  30 * time.Minute,
+		"rss_2.0_syndication":  time.Hour,
+		"atom_1.0_syndication": 24 * time.Hour,
+	}
+
+	for test, interval := range tests {
+		name := "testdata/" + test
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Reading %s: %v", name, err)
+		}
+
+		before := time.Now()
+		feed, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Parsing %s: %v", name, err)
+		}
+
+		if !feed.Refresh.After(before) || feed.Refresh.After(time.Now().Add(interval)) {
+			t.Errorf("%s: got refresh %v, want within %v", name, feed.Refresh, interval)
+		}
+		if feed.Refresh.Sub(before) > DefaultRefreshInterval && interval < DefaultRefreshInterval {
+			t.Errorf("%s: syndication module was ignored", name)
+		}
+	}
+}
+
+func TestSyndicationNext(t *testing.T) {
+	now := time.Date(2022, 3, 16, 10, 20, 0, 0, time.UTC)
+	tests := []struct {
+		sy   syndication
+		want time.Time
+	}{
+		{syndication{}, time.Time{}},
+		{syndication{UpdatePeriod: "hourly"}, now.Add(time.Hour)},
+		{syndication{UpdateFrequency: "4"}, now.Add(6 * time.Hour)},
+		{syndication{UpdatePeriod: " Daily ", UpdateFrequency: "0"}, now.Add(24 * time.Hour)},
+		{syndication{UpdatePeriod: "fortnightly"}, time.Time{}},
+		{
+			syndication{UpdatePeriod: "hourly", UpdateFrequency: "2", UpdateBase: "2000-01-01T12:00+00:00"},
+			time.Date(2022, 3, 16, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			syndication{UpdatePeriod: "daily", UpdateBase: "2022-03-01T06:00:00+01:00"},
+			time.Date(2022, 3, 17, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			syndication{UpdatePeriod: "weekly", UpdateBase: "2030-01-01"},
+			time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.sy.next(now); !got.Equal(test.want) {
+			t.Errorf("%+v: got %v, want %v", test.sy, got, test.want)
+		}
+	}
+}
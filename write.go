@@ -0,0 +1,357 @@
+package rss
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// WriteRSS2 writes f to w as an RSS 2.0 document.
+func (f *Feed) WriteRSS2(w io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	channel := &rss2_0ChannelOut{
+		Title:          f.Title,
+		Link:           f.Link,
+		Description:    f.Description,
+		Language:       f.Language,
+		ManagingEditor: f.Author,
+		Categories:     f.Categories,
+		TTL:            int(f.TTL / time.Minute),
+	}
+	if len(f.SkipHours) > 0 {
+		channel.SkipHours = &rss2_0SkipHoursOut{Hours: f.SkipHours}
+	}
+	if len(f.SkipDays) > 0 {
+		channel.SkipDays = new(rss2_0SkipDaysOut)
+		for _, day := range f.SkipDays {
+			channel.SkipDays.Days = append(channel.SkipDays.Days, day.String())
+		}
+	}
+	if f.Image != nil && f.Image.URL != "" {
+		channel.Image = &rss2_0ImageOut{
+			URL:    f.Image.URL,
+			Title:  f.Image.Title,
+			Link:   f.Link,
+			Width:  f.Image.Width,
+			Height: f.Image.Height,
+		}
+		if channel.Image.Title == "" {
+			channel.Image.Title = f.Title
+		}
+	}
+	if f.UpdateURL != "" {
+		channel.AtomLink = &atomLink{Href: f.UpdateURL, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for _, item := range f.Items {
+		next := rss2_0ItemOut{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Summary,
+			Content:     item.Content,
+			Author:      item.Author,
+			Categories:  item.Categories,
+		}
+		if item.ID != "" {
+			next.GUID = &rss2_0GUID{ID: item.ID, IsPermaLink: "false"}
+			if item.ID == item.Link {
+				next.GUID.IsPermaLink = "true"
+			}
+		}
+		if item.DateValid {
+			next.PubDate = item.Date.Format(time.RFC1123Z)
+		}
+		for _, enc := range item.Enclosures {
+			next.Enclosures = append(next.Enclosures, rss2_0EnclosureOut{URL: enc.URL, Type: enc.Type, Length: enc.Length})
+		}
+		channel.Items = append(channel.Items, next)
+	}
+
+	doc := rss2_0FeedOut{
+		Version:      "2.0",
+		XMLNSContent: "http://purl.org/rss/1.0/modules/content/",
+		XMLNSAtom:    "http://www.w3.org/2005/Atom",
+		Channel:      channel,
+	}
+
+	return writeXML(w, doc)
+}
+
+// WriteAtom writes f to w as an Atom 1.0 document.
+// The feed's id is its UpdateURL, or its Link if it
+// has none. Atom requires an id, so if f has neither,
+// ErrNoURL is returned.
+//
+// Atom also requires each entry to have an updated
+// time, so items with no valid date are given the
+// feed's, which is the newest item date or, failing
+// that, the current time. Unlike WriteRSS2 and
+// WriteJSONFeed, which leave the date out, such an
+// item therefore parses back with a valid date.
+func (f *Feed) WriteAtom(w io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.UpdateURL == "" && f.Link == "" {
+		return ErrNoURL
+	}
+
+	feed := atomFeedOut{
+		XMLNS:      "http://www.w3.org/2005/Atom",
+		ID:         f.UpdateURL,
+		Title:      f.Title,
+		Subtitle:   f.Description,
+		Updated:    f.updated().Format(time.RFC3339),
+		Language:   f.Language,
+		Categories: atomCategories(f.Categories),
+		Author:     atomAuthors(f.Author),
+	}
+	if feed.ID == "" {
+		feed.ID = f.Link
+	}
+	if f.Link != "" {
+		feed.Links = append(feed.Links, atomLink{Href: f.Link, Rel: "alternate"})
+	}
+	if f.UpdateURL != "" {
+		feed.Links = append(feed.Links, atomLink{Href: f.UpdateURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	if f.Image != nil {
+		feed.Logo = f.Image.URL
+	}
+
+	for _, item := range f.Items {
+		next := atomItemOut{
+			ID:         item.ID,
+			Title:      atomText{Text: item.Title},
+			Author:     atomAuthors(item.Author),
+			Categories: atomCategories(item.Categories),
+			Updated:    feed.Updated, // Required, even if the item has no date.
+		}
+		if item.DateValid {
+			next.Updated = item.Date.Format(time.RFC3339)
+		}
+		if item.Link != "" {
+			next.Links = append(next.Links, atomLink{Href: item.Link, Rel: "alternate"})
+		}
+		for _, enc := range item.Enclosures {
+			next.Links = append(next.Links, atomLink{Href: enc.URL, Rel: "enclosure", Type: enc.Type, Length: enc.Length})
+		}
+		if item.Summary != "" {
+			next.Summary = &atomText{Type: "html", Text: item.Summary}
+		}
+		if item.Content != "" {
+			next.Content = &atomText{Type: "html", Text: item.Content}
+		}
+		feed.Items = append(feed.Items, next)
+	}
+
+	return writeXML(w, feed)
+}
+
+// WriteJSONFeed writes f to w as a JSON Feed 1.1
+// document.
+func (f *Feed) WriteJSONFeed(w io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.UpdateURL,
+		Description: f.Description,
+		Language:    f.Language,
+		Authors:     jsonAuthors(f.Author),
+		Items:       make([]jsonItem, 0, len(f.Items)),
+		Extensions:  f.Extensions,
+	}
+	if f.Image != nil {
+		feed.Icon = f.Image.URL
+	}
+
+	for _, item := range f.Items {
+		next := jsonItem{
+			ID:          jsonID(item.ID),
+			URL:         item.Link,
+			Title:       item.Title,
+			ContentHTML: item.Content,
+			Summary:     item.Summary,
+			Authors:     jsonAuthors(item.Author),
+			Tags:        item.Categories,
+			Extensions:  item.Extensions,
+		}
+		if next.ContentHTML == "" {
+			// JSON Feed requires content_html or
+			// content_text.
+			next.ContentText = item.Summary
+			if next.ContentText == "" {
+				next.ContentText = item.Title
+			}
+		}
+		if item.Image != nil {
+			next.Image = item.Image.URL
+		}
+		if item.DateValid {
+			next.DatePublished = item.Date.Format(time.RFC3339)
+		}
+		for _, enc := range item.Enclosures {
+			next.Attachments = append(next.Attachments, jsonAttachment{URL: enc.URL, MIMEType: enc.Type, SizeInBytes: enc.Length})
+		}
+		feed.Items = append(feed.Items, next)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(&feed)
+}
+
+// updated returns the date of the most recent
+// item in f, or the current time if no item
+// has a valid date. It must be called with
+// f.mu held.
+func (f *Feed) updated() time.Time {
+	var latest time.Time
+	for _, item := range f.Items {
+		if item.DateValid && item.Date.After(latest) {
+			latest = item.Date
+		}
+	}
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	return latest
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type rss2_0FeedOut struct {
+	XMLName      xml.Name          `xml:"rss"`
+	Version      string            `xml:"version,attr"`
+	XMLNSContent string            `xml:"xmlns:content,attr"`
+	XMLNSAtom    string            `xml:"xmlns:atom,attr"`
+	Channel      *rss2_0ChannelOut `xml:"channel"`
+}
+
+type rss2_0ChannelOut struct {
+	Title          string              `xml:"title"`
+	Link           string              `xml:"link"`
+	Description    string              `xml:"description"`
+	AtomLink       *atomLink           `xml:"atom:link"`
+	Language       string              `xml:"language,omitempty"`
+	ManagingEditor string              `xml:"managingEditor,omitempty"`
+	Categories     []string            `xml:"category"`
+	Image          *rss2_0ImageOut     `xml:"image"`
+	TTL            int                 `xml:"ttl,omitempty"`
+	SkipHours      *rss2_0SkipHoursOut `xml:"skipHours"`
+	SkipDays       *rss2_0SkipDaysOut  `xml:"skipDays"`
+	Items          []rss2_0ItemOut     `xml:"item"`
+}
+
+// rss2_0SkipHoursOut and rss2_0SkipDaysOut are
+// pointers in rss2_0ChannelOut, so that they are
+// left out when empty.
+type rss2_0SkipHoursOut struct {
+	Hours []int `xml:"hour"`
+}
+
+type rss2_0SkipDaysOut struct {
+	Days []string `xml:"day"`
+}
+
+type rss2_0ImageOut struct {
+	URL    string `xml:"url"`
+	Title  string `xml:"title"`
+	Link   string `xml:"link"`
+	Width  uint32 `xml:"width,omitempty"`
+	Height uint32 `xml:"height,omitempty"`
+}
+
+type rss2_0ItemOut struct {
+	Title       string               `xml:"title,omitempty"`
+	Link        string               `xml:"link,omitempty"`
+	Description string               `xml:"description,omitempty"`
+	Content     string               `xml:"content:encoded,omitempty"`
+	Author      string               `xml:"author,omitempty"`
+	Categories  []string             `xml:"category"`
+	GUID        *rss2_0GUID          `xml:"guid"`
+	PubDate     string               `xml:"pubDate,omitempty"`
+	Enclosures  []rss2_0EnclosureOut `xml:"enclosure"`
+}
+
+type rss2_0GUID struct {
+	ID          string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+type rss2_0EnclosureOut struct {
+	URL    string `xml:"url,attr"`
+	Length uint   `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type atomFeedOut struct {
+	XMLName    xml.Name       `xml:"feed"`
+	XMLNS      string         `xml:"xmlns,attr"`
+	Language   string         `xml:"xml:lang,attr,omitempty"`
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Subtitle   string         `xml:"subtitle,omitempty"`
+	Updated    string         `xml:"updated"`
+	Author     []atomAuthor   `xml:"author"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Logo       string         `xml:"logo,omitempty"`
+	Items      []atomItemOut  `xml:"entry"`
+}
+
+type atomItemOut struct {
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Author     []atomAuthor   `xml:"author"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+func atomAuthors(name string) []atomAuthor {
+	if name == "" {
+		return nil
+	}
+	return []atomAuthor{{Name: name}}
+}
+
+func atomCategories(terms []string) []atomCategory {
+	out := make([]atomCategory, len(terms))
+	for i, term := range terms {
+		out[i].Term = term
+	}
+	return out
+}
+
+func jsonAuthors(name string) []jsonAuthor {
+	if name == "" {
+		return nil
+	}
+	return []jsonAuthor{{Name: name}}
+}
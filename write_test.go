@@ -0,0 +1,289 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	writers := map[string]func(*Feed, io.Writer) error{
+		"RSS 2.0":   (*Feed).WriteRSS2,
+		"Atom":      (*Feed).WriteAtom,
+		"JSON Feed": (*Feed).WriteJSONFeed,
+	}
+
+	for _, test := range testdataFeeds {
+		name := filepath.Join("testdata", test)
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Reading %s: %v", name, err)
+		}
+
+		want, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Parsing %s: %v", name, err)
+		}
+		if want.Link == "" {
+			// Atom needs a URL for the feed's id,
+			// which Fetch would have set.
+			want.UpdateURL = "http://example.com/" + test
+		}
+
+		for format, write := range writers {
+			buf := new(bytes.Buffer)
+			if err := write(want, buf); err != nil {
+				t.Errorf("%s: writing %s: %v", name, format, err)
+				continue
+			}
+
+			got, err := Parse(buf.Bytes())
+			if err != nil {
+				t.Errorf("%s: parsing written %s: %v\n%s", name, format, err, buf)
+				continue
+			}
+
+			if format == "JSON Feed" {
+				// JSON Feed has no feed-level categories.
+				got.Categories = want.Categories
+
+				// Items need content, so those with none
+				// are given their summary or title as text.
+				for i, item := range want.Items {
+					if item.Content != "" || i >= len(got.Items) {
+						continue
+					}
+					text := item.Summary
+					if text == "" {
+						text = item.Title
+					}
+					assertEqual(text, got.Items[i].Content, t)
+					got.Items[i].Content = ""
+				}
+			}
+			compareFeeds(t, name+" as "+format, got, want)
+		}
+	}
+}
+
+// testdataFeeds lists the well-formed feeds
+// in testdata.
+var testdataFeeds = []string{
+	"atom_1.0",
+	"atom_1.0-1",
+	"atom_1.0_enclosure",
+	"atom_1.0_html",
+	"atom_1.0_media",
+	"atom_1.0_podcast",
+	"atom_1.0_syndication",
+	"json_feed_1.0",
+	"json_feed_1.1",
+	"rss_0.91",
+	"rss_0.92",
+	"rss_1.0",
+	"rss_1.0_enclosure",
+	"rss_1.0_syndication",
+	"rss_2.0",
+	"rss_2.0-1",
+	"rss_2.0-1_enclosure",
+	"rss_2.0_content_encoded",
+	"rss_2.0_enclosure",
+	"rss_2.0_media",
+	"rss_2.0_googleplay",
+	"rss_2.0_podcast",
+	"rss_2.0_podcastindex",
+	"rss_2.0_syndication",
+	"rssupdate-1",
+	"rssupdate-2",
+}
+
+func compareFeeds(t *testing.T, name string, got, want *Feed) {
+	t.Helper()
+
+	assertEqual(want.Title, got.Title, t)
+	assertEqual(want.Description, got.Description, t)
+	assertEqual(want.Link, got.Link, t)
+	assertEqual(want.Author, got.Author, t)
+	assertEqual(want.Language, got.Language, t)
+	if want.Image.URL != "" {
+		assertEqual(want.Image.URL, got.Image.URL, t)
+	}
+	if !reflect.DeepEqual(want.Categories, got.Categories) {
+		t.Errorf("%s: got categories %q, want %q", name, got.Categories, want.Categories)
+	}
+
+	if len(got.Items) != len(want.Items) {
+		t.Errorf("%s: got %d items, want %d", name, len(got.Items), len(want.Items))
+		return
+	}
+
+	for i, w := range want.Items {
+		g := got.Items[i]
+		assertEqual(w.ID, g.ID, t)
+		assertEqual(w.Title, g.Title, t)
+		assertEqual(w.Summary, g.Summary, t)
+		assertEqual(w.Content, g.Content, t)
+		assertEqual(w.Link, g.Link, t)
+		assertEqual(w.Author, g.Author, t)
+		if !reflect.DeepEqual(w.Categories, g.Categories) {
+			t.Errorf("%s: item %d: got categories %q, want %q", name, i, g.Categories, w.Categories)
+		}
+		if !reflect.DeepEqual(w.Enclosures, g.Enclosures) {
+			t.Errorf("%s: item %d: got enclosures %v, want %v", name, i, g.Enclosures, w.Enclosures)
+		}
+		if w.DateValid && !w.Date.Equal(g.Date) {
+			t.Errorf("%s: item %d: got date %v, want %v", name, i, g.Date, w.Date)
+		}
+	}
+}
+
+func TestWriteEscaping(t *testing.T) {
+	feed := &Feed{
+		Title: `Tom & Jerry's <"Feed">`,
+		Link:  "http://example.com/?a=1&b=2",
+		Items: []*Item{{
+			ID:      "1",
+			Title:   "1 < 2 && 3 > 2",
+			Content: "<p>Some <b>bold</b> text ]]> with a CDATA terminator.</p>",
+		}},
+	}
+
+	for format, write := range map[string]func(*Feed, io.Writer) error{
+		"RSS 2.0":   (*Feed).WriteRSS2,
+		"Atom":      (*Feed).WriteAtom,
+		"JSON Feed": (*Feed).WriteJSONFeed,
+	} {
+		buf := new(bytes.Buffer)
+		if err := write(feed, buf); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if format != "JSON Feed" && strings.Contains(buf.String(), "<b>") {
+			t.Errorf("%s: content was not escaped:\n%s", format, buf)
+		}
+
+		got, err := Parse(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: parsing: %v\n%s", format, err, buf)
+		}
+		assertEqual(feed.Title, got.Title, t)
+		assertEqual(feed.Link, got.Link, t)
+		assertEqual(feed.Items[0].Title, got.Items[0].Title, t)
+		assertEqual(feed.Items[0].Content, got.Items[0].Content, t)
+	}
+}
+
+func TestWriteAtomID(t *testing.T) {
+	feed := &Feed{Title: "Feed", Link: "http://example.com/"}
+	buf := new(bytes.Buffer)
+	if err := feed.WriteAtom(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<id>http://example.com/</id>") {
+		t.Errorf("feed id is not its link:\n%s", buf)
+	}
+
+	if err := (&Feed{Title: "Feed"}).WriteAtom(new(bytes.Buffer)); err != ErrNoURL {
+		t.Errorf("got %v writing a feed with no URL, want ErrNoURL", err)
+	}
+}
+
+func TestWriteRSS2Skip(t *testing.T) {
+	feed := &Feed{Title: "Feed", Link: "http://example.com/"}
+	buf := new(bytes.Buffer)
+	if err := feed.WriteRSS2(buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "<skip") {
+		t.Errorf("empty skip hours or days were written:\n%s", buf)
+	}
+
+	feed.SkipHours = []int{0, 1}
+	feed.SkipDays = []time.Weekday{time.Sunday}
+	buf.Reset()
+	if err := feed.WriteRSS2(buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.SkipHours, feed.SkipHours) || !reflect.DeepEqual(got.SkipDays, feed.SkipDays) {
+		t.Errorf("got skip hours %v and days %v, want %v and %v", got.SkipHours, got.SkipDays, feed.SkipHours, feed.SkipDays)
+	}
+}
+
+func TestWriteAtomUndatedItem(t *testing.T) {
+	// Atom entries need an updated time, so an item
+	// with no date is given the feed's, the newest
+	// item date. The other formats leave it out.
+	date := time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC)
+	feed := &Feed{
+		Title: "Feed",
+		Link:  "http://example.com/",
+		Items: []*Item{
+			{ID: "1", Title: "Dated", Date: date, DateValid: true},
+			{ID: "2", Title: "Undated"},
+		},
+	}
+
+	for format, write := range map[string]func(*Feed, io.Writer) error{
+		"RSS 2.0":   (*Feed).WriteRSS2,
+		"Atom":      (*Feed).WriteAtom,
+		"JSON Feed": (*Feed).WriteJSONFeed,
+	} {
+		buf := new(bytes.Buffer)
+		if err := write(feed, buf); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got, err := Parse(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: parsing: %v\n%s", format, err, buf)
+		}
+
+		undated := got.Items[1]
+		if format == "Atom" {
+			if !undated.DateValid || !undated.Date.Equal(date) {
+				t.Errorf("%s: got date %v (valid %v), want the feed's updated time %v", format, undated.Date, undated.DateValid, date)
+			}
+		} else if undated.DateValid {
+			t.Errorf("%s: got date %v for an undated item", format, undated.Date)
+		}
+	}
+}
+
+func TestWriteJSONFeedContent(t *testing.T) {
+	feed := &Feed{Title: "Feed", Items: []*Item{
+		{ID: "1", Title: "HTML", Content: "<p>Content</p>"},
+		{ID: "2", Title: "Summary", Summary: "A summary"},
+		{ID: "3", Title: "Title only"},
+	}}
+	buf := new(bytes.Buffer)
+	if err := feed.WriteJSONFeed(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"content_html": "<p>Content</p>"},
+		{"content_text": "A summary"},
+		{"content_text": "Title only"},
+	}
+	for i, item := range doc.Items {
+		for _, key := range []string{"content_html", "content_text"} {
+			if item[key] != want[i][key] {
+				t.Errorf("item %d: got %s %v, want %v", i, key, item[key], want[i][key])
+			}
+		}
+	}
+}